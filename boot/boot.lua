@@ -58,136 +58,167 @@ function Moonlight:Start()
   end)
 end
 
----@return window
-function Moonlight:GetWindow()
-  return self.classes.window
+---@return Moonlight
+function GetMoonlight()
+  return Moonlight
 end
 
----@return debug
-function Moonlight:GetDebug()
-  return self.classes.debug
-end
+-- BEGIN GENERATED ACCESSORS
+-- This block is generated by 'moonlight boot sync' from every moonlight:NewClass
+-- call in the files listed in Moonlight.toc. Do not edit it by hand.
 
----@return sonataWindow
-function Moonlight:GetSonataWindow()
-  return self.classes.sonataWindow
+---@return animation
+function Moonlight:GetAnimation()
+  return self.classes.animation
 end
 
----@return Moonlight
-function GetMoonlight()
-  return Moonlight
+---@return const
+function Moonlight:GetConst()
+  return self.classes.const
 end
 
----@return pool
-function Moonlight:GetPool()
-  return self.classes.pool
-end
 ---@return container
 function Moonlight:GetContainer()
   return self.classes.container
 end
----@return animation
-function Moonlight:GetAnimation()
-  return self.classes.animation
-end
----@return tab
-function Moonlight:GetTab()
-  return self.classes.tab
+
+---@return darkroundtheme
+function Moonlight:GetDarkroundtheme()
+  return self.classes.darkroundtheme
 end
----@return grid
-function Moonlight:GetGrid()
-  return self.classes.grid
+
+---@return darktheme
+function Moonlight:GetDarktheme()
+  return self.classes.darktheme
 end
----@return section
-function Moonlight:GetSection()
-  return self.classes.section
+
+---@return debug
+function Moonlight:GetDebug()
+  return self.classes.debug
 end
----@return item
-function Moonlight:GetItem()
-  return self.classes.item
+
+---@return defaulttheme
+function Moonlight:GetDefaulttheme()
+  return self.classes.defaulttheme
 end
----@return loader
-function Moonlight:GetLoader()
-  return self.classes.loader
+
+---@return drawable
+function Moonlight:GetDrawable()
+  return self.classes.drawable
 end
+
 ---@return event
 function Moonlight:GetEvent()
   return self.classes.event
 end
----@return const
-function Moonlight:GetConst()
-  return self.classes.const
+
+---@return grid
+function Moonlight:GetGrid()
+  return self.classes.grid
+end
+
+---@return item
+function Moonlight:GetItem()
+  return self.classes.item
 end
+
 ---@return itembutton
 function Moonlight:GetItembutton()
   return self.classes.itembutton
 end
----@return placeholderbutton
-function Moonlight:GetPlaceholderbutton()
-  return self.classes.placeholderbutton
-end
----@return sectionset
-function Moonlight:GetSectionset()
-  return self.classes.sectionset
-end
----@return sonataEngine
-function Moonlight:GetSonataEngine()
-  return self.classes.sonataEngine
-end
----@return defaulttheme
-function Moonlight:GetDefaulttheme()
-  return self.classes.defaulttheme
-end
----@return tabbutton
-function Moonlight:GetTabbutton()
-  return self.classes.tabbutton
-end
----@return stub
-function Moonlight:GetStub()
-  return self.classes.stub
+
+---@return list
+function Moonlight:GetList()
+  return self.classes.list
 end
 
----@return darktheme
-function Moonlight:GetDarktheme()
-  return self.classes.darktheme
+---@return listrow
+function Moonlight:GetListrow()
+  return self.classes.listrow
 end
----@return darkroundtheme
-function Moonlight:GetDarkroundtheme()
-  return self.classes.darkroundtheme
+
+---@return loader
+function Moonlight:GetLoader()
+  return self.classes.loader
 end
+
 ---@return parchmenttheme
 function Moonlight:GetParchmenttheme()
   return self.classes.parchmenttheme
 end
+
+---@return placeholderbutton
+function Moonlight:GetPlaceholderbutton()
+  return self.classes.placeholderbutton
+end
+
+---@return pool
+function Moonlight:GetPool()
+  return self.classes.pool
+end
+
 ---@return popup
 function Moonlight:GetPopup()
   return self.classes.popup
 end
+
 ---@return render
 function Moonlight:GetRender()
   return self.classes.render
 end
----@return list
-function Moonlight:GetList()
-  return self.classes.list
-end
----@return listrow
-function Moonlight:GetListrow()
-  return self.classes.listrow
+
+---@return save
+function Moonlight:GetSave()
+  return self.classes.save
 end
+
 ---@return scrollbox
 function Moonlight:GetScrollbox()
   return self.classes.scrollbox
 end
----@return drawable
-function Moonlight:GetDrawable()
-  return self.classes.drawable
+
+---@return section
+function Moonlight:GetSection()
+  return self.classes.section
+end
+
+---@return sectionset
+function Moonlight:GetSectionset()
+  return self.classes.sectionset
+end
+
+---@return sonataEngine
+function Moonlight:GetSonataEngine()
+  return self.classes.sonataEngine
 end
+
+---@return sonataWindow
+function Moonlight:GetSonataWindow()
+  return self.classes.sonataWindow
+end
+
+---@return stub
+function Moonlight:GetStub()
+  return self.classes.stub
+end
+
+---@return tab
+function Moonlight:GetTab()
+  return self.classes.tab
+end
+
+---@return tabbutton
+function Moonlight:GetTabbutton()
+  return self.classes.tabbutton
+end
+
 ---@return util
 function Moonlight:GetUtil()
   return self.classes.util
 end
----@return save
-function Moonlight:GetSave()
-  return self.classes.save
-end
\ No newline at end of file
+
+---@return window
+function Moonlight:GetWindow()
+  return self.classes.window
+end
+-- END GENERATED ACCESSORS
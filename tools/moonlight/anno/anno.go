@@ -1,23 +1,28 @@
 package anno
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
 	"github.com/Cidan/Moonlight/tools/moonlight/util"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/sourcegraph/conc/pool"
 	"github.com/spf13/cobra"
 )
 
+// ErrDrift is returned by anno update --check when a source differs from
+// its lock entry.
+var ErrDrift = errors.New("annotations drifted from " + LockFile + ", run anno update")
+
 func NewAnnoCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "anno",
@@ -29,100 +34,268 @@ func NewAnnoCmd() *cobra.Command {
 	return cmd
 }
 
-type repoInfo struct {
-	URL           string
-	Name          string
-	SubDirs       []string
-	AnnotateMixin bool
-	Branch        string
-	Tag           string
-}
-
 func newUpdateCmd() *cobra.Command {
+	var (
+		dryRun      bool
+		check       bool
+		frozen      bool
+		gameVersion string
+	)
+
 	cmd := &cobra.Command{
 		Use:   "update",
-		Short: "Update annotations from a git repository",
+		Short: "Update annotations from their sources",
+		Long: `Fetches every source listed in annotations.yaml at the repo root and copies its subdirs to
+annotations/<name>, applying the post-processors of the source. A source is a git URL with an
+optional tag or branch ref, a local directory or a tarball, so the annotations can also be
+updated offline from a local mirror.
+
+Git sources are fetched through a clone cache under the user cache directory, which is kept
+between runs and only fetches new commits. The version of every source, the commit of a git
+source or the digest of a dir or tarball, is recorded in annotations.lock. Sources whose
+version and config match their lock entry are skipped.
+
+With --check, nothing is fetched or written; every source whose upstream version differs from
+the lock is reported and the command fails. With --frozen, the locked versions are fetched
+instead of the latest ones, which reproduces the annotations exactly.
+
+A source with game-version: yes follows the game version of the retail ## Interface: in
+Moonlight.toc, e.g. tag 11.1.5 for 110105. Without a ref, its ref is derived from the TOC;
+with a ref that disagrees with the TOC, a warning is printed. --game-version 11.2.0 sets both
+the TOC interface and the pinned ref, then updates.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repos := []repoInfo{
-				{
-					URL:     "https://github.com/Ketho/vscode-wow-api",
-					Name:    "vscode-wow-api",
-					SubDirs: []string{"Annotations/Core"},
-				},
-				{
-					URL:           "https://github.com/Gethe/wow-ui-source",
-					Name:          "wow-ui-source",
-					SubDirs:       []string{"Interface/AddOns"},
-					AnnotateMixin: true,
-					Branch:        "live",
-					Tag:           "11.2.0",
-				},
+			if check && frozen {
+				return fmt.Errorf("--check and --frozen can not be used together")
+			}
+			if gameVersion != "" && (check || frozen) {
+				return fmt.Errorf("--game-version can not be used with --check or --frozen")
 			}
-
 			reporoot, err := util.FindRepoRoot()
 			if err != nil {
 				return err
 			}
+			cfg, err := LoadConfig(reporoot)
+			if err != nil {
+				return err
+			}
+			lock, err := LoadLock(reporoot)
+			if err != nil {
+				return err
+			}
 
-			for _, repo := range repos {
-				tempDir, err := os.MkdirTemp("", "wow-api-repo-")
-				if err != nil {
-					return fmt.Errorf("failed to create temp dir: %w", err)
+			cs := changeset.New(reporoot)
+			if gameVersion != "" {
+				if cfg, err = setGameVersion(cs, reporoot, cfg, gameVersion); err != nil {
+					return err
 				}
-				defer os.RemoveAll(tempDir)
+			}
+			if err := resolveGameVersion(cs, reporoot, cfg); err != nil {
+				return err
+			}
 
-				cloneOptions := &git.CloneOptions{
-					URL:      repo.URL,
-					Progress: os.Stdout,
-					Depth:    1,
+			if check {
+				drifted, err := checkLock(reporoot, cfg, lock)
+				if err != nil {
+					return err
+				}
+				if drifted > 0 {
+					fmt.Printf("%d of %d sources drifted\n", drifted, len(cfg.Sources))
+					// The drift is the expected outcome of a check, not a
+					// misuse of the command.
+					cmd.SilenceUsage = true
+					cmd.SilenceErrors = true
+					return ErrDrift
 				}
+				fmt.Printf("All %d sources match %s\n", len(cfg.Sources), LockFile)
+				return nil
+			}
 
-				if repo.Tag != "" {
-					cloneOptions.ReferenceName = plumbing.NewTagReferenceName(repo.Tag)
-				} else if repo.Branch != "" {
-					cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(repo.Branch)
+			// Every source is copied and processed in a staging dir first, and
+			// only then staged into the repo, so that a failed update never
+			// leaves the annotations half written.
+			stageRoot, err := os.MkdirTemp("", "moonlight-anno-")
+			if err != nil {
+				return fmt.Errorf("failed to create staging dir: %w", err)
+			}
+			defer os.RemoveAll(stageRoot)
+
+			newLock := &Lock{}
+			var staged []Source
+			for _, source := range cfg.Sources {
+				entry, locked := lock.Entry(source.Name)
+				locked = locked && entry.Matches(source)
+
+				pin := ""
+				if frozen {
+					if !locked {
+						return fmt.Errorf("%s has no entry for %s with its current config, run anno update without --frozen", LockFile, source.Name)
+					}
+					// The locked type lets a cached git source be reproduced
+					// while its local mirror is missing.
+					source.Type = entry.Type
+					pin = entry.Version
+				} else if locked && installed(reporoot, source) {
+					latest, err := source.Latest(reporoot)
+					if err != nil {
+						return fmt.Errorf("failed to update %s: %w", source.Name, err)
+					}
+					if latest == entry.Version {
+						fmt.Printf("%s is up to date at %s\n", source.Name, ShortVersion(latest))
+						newLock.Sources = append(newLock.Sources, entry)
+						continue
+					}
 				}
 
-				_, err = git.PlainClone(tempDir, false, cloneOptions)
+				version, err := stageSource(reporoot, stageRoot, source, pin)
 				if err != nil {
-					return fmt.Errorf("failed to clone repo: %w", err)
+					return fmt.Errorf("failed to update %s: %w", source.Name, err)
 				}
+				fmt.Printf("Staged %s at %s\n", source.Name, ShortVersion(version))
+				newLock.Sources = append(newLock.Sources, NewLockEntry(source, source.Kind(reporoot), version))
+				staged = append(staged, source)
+			}
 
-				for _, subDir := range repo.SubDirs {
-					sourceDir := filepath.Join(tempDir, subDir)
-					destDir := filepath.Join(reporoot, "annotations", repo.Name, subDir)
-
-					if _, err := os.Stat(destDir); !os.IsNotExist(err) {
-						if err := os.RemoveAll(destDir); err != nil {
-							return fmt.Errorf("failed to remove existing destination directory: %w", err)
-						}
-					}
-
-					if err := os.MkdirAll(destDir, 0755); err != nil {
-						return fmt.Errorf("failed to create destination directory: %w", err)
-					}
-
-					if err := util.CopyDir(sourceDir, destDir); err != nil {
-						return fmt.Errorf("failed to copy files: %w", err)
+			var generated bytes.Buffer
+			for _, source := range staged {
+				if !source.Processes(ProcessMixins) {
+					continue
+				}
+				for _, subDir := range source.SubDirs {
+					classes, err := processMixinAnnotations(filepath.Join(stageRoot, source.Name, subDir), classDirs(cfg, source, stageRoot, reporoot))
+					if err != nil {
+						return fmt.Errorf("failed to process mixin annotations: %w", err)
 					}
+					generated.Write(classes)
+				}
+			}
+			if generated.Len() > 0 {
+				cs.Write(filepath.Join(reporoot, "annotations", "generated", "generated.lua"), append([]byte("---@meta\n\n"), generated.Bytes()...), 0644)
+			}
 
-					if err := processMetaAnnotations(destDir); err != nil {
-						return fmt.Errorf("failed to process meta annotations: %w", err)
+			for _, source := range staged {
+				for _, subDir := range source.SubDirs {
+					stageDir := filepath.Join(stageRoot, source.Name, subDir)
+					destDir := filepath.Join(reporoot, "annotations", source.Name, subDir)
+					if err := cs.SyncDir(stageDir, destDir); err != nil {
+						return err
 					}
+				}
+			}
 
-					if repo.AnnotateMixin {
-						if err := processMixinAnnotations(destDir, reporoot); err != nil {
-							return fmt.Errorf("failed to process mixin annotations: %w", err)
-						}
-					}
+			// A frozen update reproduces the lock, it never changes it.
+			if !frozen {
+				lockPath := filepath.Join(reporoot, LockFile)
+				content, err := newLock.Bytes()
+				if err != nil {
+					return err
 				}
+				if current, _ := os.ReadFile(lockPath); !bytes.Equal(current, content) {
+					cs.Write(lockPath, content, 0644)
+				}
+			}
+
+			if cs.Empty() {
+				fmt.Println("Annotations are up to date")
+				return nil
+			}
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to write annotations: %w", err)
 			}
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+	cmd.Flags().BoolVar(&check, "check", false, "Report the sources whose upstream version differs from "+LockFile)
+	cmd.Flags().BoolVar(&frozen, "frozen", false, "Fetch the versions recorded in "+LockFile+" instead of the latest ones")
+	cmd.Flags().StringVar(&gameVersion, "game-version", "", "Set the game version, e.g. 11.2.0, in Moonlight.toc and "+ConfigFile+" before updating")
 	return cmd
 }
 
+// installed reports if every subdir of source is in annotations/.
+func installed(reporoot string, source Source) bool {
+	for _, subDir := range source.SubDirs {
+		if _, err := os.Stat(filepath.Join(reporoot, "annotations", source.Name, subDir)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLock prints how every source compares to its lock entry and returns
+// the number of sources that drifted from it.
+func checkLock(reporoot string, cfg *Config, lock *Lock) (int, error) {
+	drifted := 0
+	for _, source := range cfg.Sources {
+		entry, locked := lock.Entry(source.Name)
+		switch {
+		case !locked:
+			fmt.Printf("%s: not locked\n", source.Name)
+		case !entry.Matches(source):
+			fmt.Printf("%s: the config changed since it was locked\n", source.Name)
+		default:
+			latest, err := source.Latest(reporoot)
+			if err != nil {
+				return 0, fmt.Errorf("failed to check %s: %w", source.Name, err)
+			}
+			if latest == entry.Version {
+				fmt.Printf("%s: up to date at %s\n", source.Name, ShortVersion(latest))
+				continue
+			}
+			fmt.Printf("%s: locked at %s, upstream is at %s\n", source.Name, ShortVersion(entry.Version), ShortVersion(latest))
+		}
+		drifted++
+	}
+	for _, entry := range lock.Sources {
+		if !slices.ContainsFunc(cfg.Sources, func(s Source) bool { return s.Name == entry.Name }) {
+			fmt.Printf("%s: locked but no longer configured\n", entry.Name)
+			drifted++
+		}
+	}
+	return drifted, nil
+}
+
+// stageSource fetches the source, at pin when it is set, and copies its
+// subdirs to stageRoot/<name>, applying the meta post-processor. It returns
+// the version it fetched. Mixins are processed once every source is
+// staged, as they depend on the classes of the others.
+func stageSource(reporoot, stageRoot string, source Source, pin string) (string, error) {
+	fetchDir, err := os.MkdirTemp("", "moonlight-source-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(fetchDir)
+
+	fmt.Printf("Fetching %s from %s\n", source.Name, source.Location(reporoot))
+	version, err := source.Fetch(reporoot, fetchDir, pin)
+	if err != nil {
+		return "", err
+	}
+
+	for _, subDir := range source.SubDirs {
+		sourceDir := filepath.Join(fetchDir, filepath.FromSlash(subDir))
+		stageDir := filepath.Join(stageRoot, source.Name, filepath.FromSlash(subDir))
+
+		info, err := os.Stat(sourceDir)
+		if err != nil || !info.IsDir() {
+			return "", fmt.Errorf("subdir %s does not exist in the source", subDir)
+		}
+		if err := os.MkdirAll(stageDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		if err := util.CopyDir(sourceDir, stageDir); err != nil {
+			return "", fmt.Errorf("failed to copy files: %w", err)
+		}
+		if source.Processes(ProcessMeta) {
+			if err := processMetaAnnotations(stageDir); err != nil {
+				return "", fmt.Errorf("failed to process meta annotations: %w", err)
+			}
+		}
+	}
+	return version, nil
+}
+
 func processMetaAnnotations(destDir string) error {
 	return filepath.Walk(destDir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
@@ -146,12 +319,41 @@ func processMetaAnnotations(destDir string) error {
 	})
 }
 
-func processMixinAnnotations(destDir string, reporoot string) error {
-	kethoClasses := make(map[string]bool)
-	kethoDir := filepath.Join(reporoot, "annotations", "vscode-wow-api", "Annotations/Core")
-	if _, err := os.Stat(kethoDir); !os.IsNotExist(err) {
-		fmt.Println("Scanning Ketho annotations for existing classes...")
-		err := filepath.Walk(kethoDir, func(path string, info fs.FileInfo, err error) error {
+// classDirs returns the annotation dirs of every source other than
+// source, whose classes are left alone by the mixin annotator. The dirs
+// staged in this run are used, or the ones already in the repo when a
+// source was not staged.
+func classDirs(cfg *Config, source Source, stageRoot, reporoot string) []string {
+	var dirs []string
+	for _, other := range cfg.Sources {
+		if other.Name == source.Name {
+			continue
+		}
+		for _, subDir := range other.SubDirs {
+			staged := filepath.Join(stageRoot, other.Name, subDir)
+			if _, err := os.Stat(staged); err == nil {
+				dirs = append(dirs, staged)
+			} else {
+				dirs = append(dirs, filepath.Join(reporoot, "annotations", other.Name, subDir))
+			}
+		}
+	}
+	return dirs
+}
+
+// processMixinAnnotations adds a ---@class line above every mixin in the Lua
+// files under destDir, and returns the generated mixin inheritance
+// annotations, along with the fields that the methods of the mixins assign
+// to self and stubs for their undocumented methods. Classes and members
+// declared in classDirs, such as the Ketho annotations, are left alone.
+func processMixinAnnotations(destDir string, classDirs []string) ([]byte, error) {
+	kethoClasses := make(classIndex)
+	for _, dir := range classDirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		fmt.Printf("Scanning %s for existing classes...\n", dir)
+		err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -160,16 +362,12 @@ func processMixinAnnotations(destDir string, reporoot string) error {
 				if err != nil {
 					return err
 				}
-				reClass := regexp.MustCompile(`---@class\s+([\w\d_]+)`)
-				matches := reClass.FindAllStringSubmatch(string(content), -1)
-				for _, match := range matches {
-					kethoClasses[match[1]] = true
-				}
+				kethoClasses.scan(string(content))
 			}
 			return nil
 		})
 		if err != nil {
-			return fmt.Errorf("failed to scan ketho annotations: %w", err)
+			return nil, fmt.Errorf("failed to scan %s for classes: %w", dir, err)
 		}
 	}
 
@@ -189,7 +387,7 @@ func processMixinAnnotations(destDir string, reporoot string) error {
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	xmlPool := pool.New().WithErrors()
@@ -235,173 +433,88 @@ func processMixinAnnotations(destDir string, reporoot string) error {
 		})
 	}
 	if err := xmlPool.Wait(); err != nil {
-		return err
+		return nil, err
 	}
 
-	luaFiles := &sync.Map{}
-	allLuaMixins := &sync.Map{} // mixinVar -> path
-	fmt.Println("Loading Lua files into memory...")
+	var luaFiles []string
 	err = filepath.Walk(destDir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() && strings.HasSuffix(info.Name(), ".lua") {
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			luaFiles.Store(path, content)
-
-			reMixin := regexp.MustCompile(`(?m)^([\w\d_]+)\s*=\s*\n?(?:CreateFromMixins\(([^)]+)\);?|{)`)
-			matches := reMixin.FindAllStringSubmatch(string(content), -1)
-			for _, match := range matches {
-				allLuaMixins.Store(match[1], path)
-			}
+			luaFiles = append(luaFiles, path)
 		}
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Println("Annotating mixins...")
-	for pass := 0; ; pass++ {
-		changesMadeInPass := false
-		luaFiles.Range(func(pathKey, contentKey interface{}) bool {
-			path := pathKey.(string)
-			content := contentKey.([]byte)
-
-			reMixin := regexp.MustCompile(`(?m)^([\w\d_]+)\s*=\s*\n?(?:CreateFromMixins\(([^)]+)\);?|{)`)
-			reAnnotation := regexp.MustCompile(`---@class`)
-
-			matches := reMixin.FindAllSubmatchIndex(content, -1)
-			if len(matches) == 0 {
-				return true
-			}
-
-			fileChanged := false
-			for i := len(matches) - 1; i >= 0; i-- {
-				match := matches[i]
-				matchStart := match[0]
-
-				lineStart := 0
-				if j := strings.LastIndex(string(content[:matchStart]), "\n"); j != -1 {
-					lineStart = j + 1
-				}
-
-				isAnnotated := false
-				if lineStart > 0 {
-					prevLineStart := 0
-					if k := strings.LastIndex(string(content[:lineStart-1]), "\n"); k != -1 {
-						prevLineStart = k + 1
-					}
-					prevLine := content[prevLineStart:lineStart]
-					if reAnnotation.Match(prevLine) {
-						isAnnotated = true
-					}
-				}
-
-				if !isAnnotated {
-					mixinVar := string(content[match[2]:match[3]])
-					if _, exists := kethoClasses[mixinVar]; exists {
-						continue
-					}
-					parentsStr := ""
-					if match[4] != -1 && match[5] != -1 {
-						parentsStr = string(content[match[4]:match[5]])
-					}
-
-					var annotation string
-					if parentsStr != "" {
-						parentsList := strings.Split(parentsStr, ",")
-						var cleanedParents []string
-						for _, p := range parentsList {
-							cleanedParents = append(cleanedParents, strings.TrimSpace(p))
-						}
-						annotation = fmt.Sprintf("---@class %s: %s\n", mixinVar, strings.Join(cleanedParents, ", "))
-					} else {
-						annotation = fmt.Sprintf("---@class %s\n", mixinVar)
-					}
-
-					newContent := make([]byte, 0, len(content)+len(annotation))
-					newContent = append(newContent, content[:lineStart]...)
-					newContent = append(newContent, []byte(annotation)...)
-					newContent = append(newContent, content[lineStart:]...)
-					content = newContent
-					fileChanged = true
-				}
+	fmt.Println("Parsing Lua files...")
+	parsed := make([]luaFile, len(luaFiles))
+	parsePool := pool.New().WithErrors()
+	for i, path := range luaFiles {
+		i, path := i, path
+		parsePool.Go(func() error {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
 			}
-
-			if fileChanged {
-				luaFiles.Store(path, content)
-				changesMadeInPass = true
+			file, err := parseLuaFile(path, content)
+			if err != nil {
+				// A file the parser can not read is left as it is, rather
+				// than failing the whole update.
+				rel, _ := filepath.Rel(destDir, path)
+				fmt.Printf("Warning: failed to parse %s, its mixins are not annotated: %v\n", rel, err)
+				return nil
 			}
-			return true
+			parsed[i] = file
+			return nil
 		})
-
-		if !changesMadeInPass {
-			break
-		}
-
-		if pass > 15 {
-			fmt.Println("Warning: Exceeded max annotation passes, check for circular dependencies.")
-			break
-		}
 	}
-
-	fmt.Println("Verifying annotations...")
-	allLuaMixins.Range(func(mixinKey, pathKey interface{}) bool {
-		mixinVar := mixinKey.(string)
-		path := pathKey.(string)
-
-		finalContentBytes, _ := luaFiles.Load(path)
-		content := finalContentBytes.([]byte)
-
-		reMixin := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(mixinVar) + `\s*=\s*\n?(?:CreateFromMixins\([^)]+\);?|{)`)
-		reAnnotation := regexp.MustCompile(`---@class`)
-
-		match := reMixin.FindIndex(content)
-		if match == nil {
-			return true
-		}
-
-		matchStart := match[0]
-		lineStart := 0
-		if j := strings.LastIndex(string(content[:matchStart]), "\n"); j != -1 {
-			lineStart = j + 1
-		}
-
-		isAnnotated := false
-		if lineStart > 0 {
-			prevLineStart := 0
-			if k := strings.LastIndex(string(content[:lineStart-1]), "\n"); k != -1 {
-				prevLineStart = k + 1
-			}
-			prevLine := content[prevLineStart:lineStart]
-			if reAnnotation.Match(prevLine) {
-				isAnnotated = true
+	if err := parsePool.Wait(); err != nil {
+		return nil, err
+	}
+	var xmlMixins []string
+	mixinToName.Range(func(key, value interface{}) bool {
+		for _, mixin := range strings.Split(key.(string), ",") {
+			if mixin = strings.TrimSpace(mixin); mixin != "" {
+				xmlMixins = append(xmlMixins, mixin)
 			}
 		}
-
-		if !isAnnotated {
-			fmt.Printf("Warning: Could not find annotation for mixin variable %s in %s\n", mixinVar, path)
-		}
-
 		return true
 	})
+	mixins := newMixinSet(parsed, xmlMixins)
 
-	fmt.Println("Writing changes to disk...")
-	luaFiles.Range(func(key, value interface{}) bool {
-		path := key.(string)
-		content := value.([]byte)
-		info, _ := os.Stat(path)
-		os.WriteFile(path, content, info.Mode())
-		return true
-	})
+	fmt.Println("Annotating mixins...")
+	annotatePool := pool.New().WithErrors()
+	for _, file := range parsed {
+		if file.Path == "" {
+			continue
+		}
+		file := file
+		annotatePool.Go(func() error {
+			content, err := os.ReadFile(file.Path)
+			if err != nil {
+				return err
+			}
+			annotated, n := mixins.annotate(content, file, kethoClasses)
+			if n == 0 {
+				return nil
+			}
+			info, err := os.Stat(file.Path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(file.Path, annotated, info.Mode())
+		})
+	}
+	if err := annotatePool.Wait(); err != nil {
+		return nil, err
+	}
 
 	fmt.Println("Generating mixin inheritance file...")
 	var generatedContent strings.Builder
-	generatedContent.WriteString("---@meta\n\n")
 
 	nameToParents := make(map[string][]string)
 	mixinToName.Range(func(key, value interface{}) bool {
@@ -484,7 +597,7 @@ func processMixinAnnotations(destDir string, reporoot string) error {
 		if strings.HasPrefix(name, "$") {
 			continue
 		}
-		if _, exists := kethoClasses[name]; exists {
+		if kethoClasses.declared(name) {
 			continue
 		}
 		parents := getFullHierarchy(name, make(map[string]bool))
@@ -492,11 +605,7 @@ func processMixinAnnotations(destDir string, reporoot string) error {
 			generatedContent.WriteString(fmt.Sprintf("---@class %s: %s\n\n", name, strings.Join(parents, ", ")))
 		}
 	}
+	generatedContent.WriteString(mixins.memberAnnotations(kethoClasses))
 
-	generatedPath := filepath.Join(reporoot, "annotations", "generated", "generated.lua")
-	if err := os.WriteFile(generatedPath, []byte(generatedContent.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write generated annotations file: %w", err)
-	}
-
-	return nil
+	return []byte(generatedContent.String()), nil
 }
@@ -0,0 +1,268 @@
+package anno
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// cache is the clone cache of a git source: a bare repository under the
+// user cache dir, keyed by URL and ref, that is kept between runs and only
+// fetches what it does not have yet.
+type cache struct {
+	dir  string
+	repo *git.Repository
+}
+
+// CacheDir returns the directory that holds the clone caches.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "moonlight", "anno"), nil
+}
+
+// openCache opens the clone cache of url at ref, creating it if needed.
+func openCache(url, ref string) (*cache, error) {
+	root, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(url + "\n" + ref))
+	name := strings.TrimSuffix(path.Base(filepath.ToSlash(url)), ".git")
+	dir := filepath.Join(root, name+"-"+hex.EncodeToString(sum[:8]))
+
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if repo, err = git.PlainInit(dir, true); err == nil {
+			_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the clone cache %s: %w", dir, err)
+	}
+	return &cache{dir: dir, repo: repo}, nil
+}
+
+// fetch fetches spec from origin, the last depth commits of it, or its
+// whole history when depth is 0.
+func (c *cache) fetch(spec string, depth int) error {
+	err := c.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(spec)},
+		Depth:      depth,
+		Tags:       git.NoTags,
+		Force:      true,
+		Progress:   os.Stdout,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch %s: %w", spec, err)
+	}
+	return nil
+}
+
+// peel returns the commit hash points to, following annotated tags, and if
+// the cache has that commit.
+func (c *cache) peel(hash plumbing.Hash) (plumbing.Hash, bool) {
+	for {
+		if tag, err := c.repo.TagObject(hash); err == nil {
+			hash = tag.Target
+			continue
+		}
+		_, err := c.repo.CommitObject(hash)
+		return hash, err == nil
+	}
+}
+
+// resolve returns the commit the cached ref name points to.
+func (c *cache) resolve(name plumbing.ReferenceName) (plumbing.Hash, error) {
+	r, err := c.repo.Reference(name, true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("the clone cache has no %s: %w", name, err)
+	}
+	hash, ok := c.peel(r.Hash())
+	if !ok {
+		return plumbing.ZeroHash, fmt.Errorf("the clone cache has no commit for %s", name)
+	}
+	return hash, nil
+}
+
+// extract writes the files of subdirs at commit to the same paths under
+// dir.
+func (c *cache) extract(commit plumbing.Hash, subdirs []string, dir string) error {
+	obj, err := c.repo.CommitObject(commit)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+	tree, err := obj.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read the tree of %s: %w", commit, err)
+	}
+	for _, subdir := range subdirs {
+		sub, err := tree.Tree(subdir)
+		if err != nil {
+			return fmt.Errorf("subdir %s does not exist at %s", subdir, commit)
+		}
+		err = sub.Files().ForEach(func(f *object.File) error {
+			if f.Mode != filemode.Regular && f.Mode != filemode.Executable {
+				return nil
+			}
+			target := filepath.Join(dir, filepath.FromSlash(subdir), filepath.FromSlash(f.Name))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			r, err := f.Reader()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			_, err = io.Copy(out, r)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", subdir, err)
+		}
+	}
+	return nil
+}
+
+// remoteRef finds the reference that ref names in the repository at url,
+// a tag or else a branch, and the hash it points to. Annotated tags are
+// peeled when the server advertises the commit they point to. An empty ref
+// is the default branch.
+func remoteRef(url, ref string) (plumbing.ReferenceName, plumbing.Hash, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	refs, err := remote.List(&git.ListOptions{PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return "", plumbing.ZeroHash, fmt.Errorf("failed to list the refs of %s: %w", url, err)
+	}
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, r := range refs {
+		byName[r.Name()] = r
+	}
+
+	var names []plumbing.ReferenceName
+	if ref == "" {
+		head, ok := byName[plumbing.HEAD]
+		if !ok {
+			return "", plumbing.ZeroHash, fmt.Errorf("%s has no default branch", url)
+		}
+		if head.Type() == plumbing.SymbolicReference {
+			names = append(names, head.Target())
+		} else {
+			// Without the symref capability, the default branch is the
+			// branch HEAD points at.
+			for _, r := range refs {
+				if r.Name().IsBranch() && r.Hash() == head.Hash() {
+					names = append(names, r.Name())
+				}
+			}
+			slices.Sort(names)
+		}
+	} else {
+		names = append(names, plumbing.NewTagReferenceName(ref), plumbing.NewBranchReferenceName(ref))
+	}
+
+	for _, name := range names {
+		r, ok := byName[name]
+		if !ok {
+			continue
+		}
+		hash := r.Hash()
+		if peeled, ok := byName[name+"^{}"]; ok {
+			hash = peeled.Hash()
+		}
+		return name, hash, nil
+	}
+	if ref == "" {
+		return "", plumbing.ZeroHash, fmt.Errorf("failed to find the default branch of %s", url)
+	}
+	return "", plumbing.ZeroHash, fmt.Errorf("%s has no tag or branch named %s", url, ref)
+}
+
+// latestGit returns the commit ref of the repository at url points to now.
+func latestGit(url, ref string) (string, error) {
+	_, hash, err := remoteRef(url, ref)
+	if err != nil {
+		return "", err
+	}
+	// An annotated tag that the server did not peel is peeled with the
+	// cache, if it has the tag.
+	if c, err := openCache(url, ref); err == nil {
+		hash, _ = c.peel(hash)
+	}
+	return hash.String(), nil
+}
+
+// fetchGit extracts subdirs of the repository at url into dir, through the
+// clone cache, and returns the commit it extracted: the commit ref points
+// to, or pin when it is set. A pinned commit that is already cached needs
+// no network access; otherwise it is fetched by its hash, or with the
+// history of ref when the server does not serve commits by hash.
+func fetchGit(url, ref, pin string, subdirs []string, dir string) (string, error) {
+	c, err := openCache(url, ref)
+	if err != nil {
+		return "", err
+	}
+
+	var commit plumbing.Hash
+	if pin != "" {
+		commit = plumbing.NewHash(pin)
+		if _, ok := c.peel(commit); !ok {
+			fmt.Printf("Fetching commit %s into %s\n", pin, c.dir)
+			if err := c.fetch(fmt.Sprintf("%s:refs/pins/%s", pin, pin), 1); err != nil {
+				name, _, listErr := remoteRef(url, ref)
+				if listErr != nil {
+					return "", listErr
+				}
+				fmt.Printf("Fetching the history of %s into %s\n", name.Short(), c.dir)
+				if err := c.fetch(fmt.Sprintf("+%s:%s", name, name), 0); err != nil {
+					return "", err
+				}
+			}
+			if _, ok := c.peel(commit); !ok {
+				return "", fmt.Errorf("locked commit %s is no longer in %s", pin, url)
+			}
+		}
+	} else {
+		name, upstream, err := remoteRef(url, ref)
+		if err != nil {
+			return "", err
+		}
+		var ok bool
+		if commit, ok = c.peel(upstream); !ok {
+			fmt.Printf("Fetching %s into %s\n", name.Short(), c.dir)
+			if err := c.fetch(fmt.Sprintf("+%s:%s", name, name), 1); err != nil {
+				return "", err
+			}
+			if commit, err = c.resolve(name); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := c.extract(commit, subdirs, dir); err != nil {
+		return "", err
+	}
+	return commit.String(), nil
+}
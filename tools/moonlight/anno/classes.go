@@ -0,0 +1,106 @@
+package anno
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	reClassDecl = regexp.MustCompile(`^---@class\s+(?:\(\w+\)\s+)?([\w.]+)\s*(?::\s*(.*))?`)
+	reFieldDecl = regexp.MustCompile(`^---@field\s+(?:(?:public|private|protected|package)\s+)?(\w+)`)
+	reFuncDecl  = regexp.MustCompile(`^function\s+([\w.]+)[:.](\w+)\s*\(`)
+)
+
+// classInfo is what a set of annotations declares about a class.
+type classInfo struct {
+	// Declared is true when a ---@class line declares the class, rather
+	// than only functions being defined on it.
+	Declared bool
+	Parents  []string
+	Members  map[string]bool
+}
+
+// classIndex holds the classes declared by annotations, such as the Ketho
+// annotations, by name.
+type classIndex map[string]*classInfo
+
+// class returns the entry of name, and creates it if there is none.
+func (c classIndex) class(name string) *classInfo {
+	info, ok := c[name]
+	if !ok {
+		info = &classInfo{Members: make(map[string]bool)}
+		c[name] = info
+	}
+	return info
+}
+
+// declared reports if name is declared as a class.
+func (c classIndex) declared(name string) bool {
+	info, ok := c[name]
+	return ok && info.Declared
+}
+
+// scan adds the classes declared in the Lua source content, along with the
+// fields of their ---@field lines and the functions defined on them.
+func (c classIndex) scan(content string) {
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if match := reClassDecl.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			info := c.class(current)
+			info.Declared = true
+			for _, parent := range strings.Split(match[2], ",") {
+				// Generic parents such as Foo<T> are named without their
+				// type arguments.
+				parent, _, _ = strings.Cut(strings.TrimSpace(parent), "<")
+				if parent != "" {
+					info.Parents = append(info.Parents, parent)
+				}
+			}
+			continue
+		}
+		if match := reFieldDecl.FindStringSubmatch(line); match != nil && current != "" {
+			c[current].Members[match[1]] = true
+			continue
+		}
+		if strings.HasPrefix(line, "---") {
+			continue
+		}
+		// Any other line ends the annotations of the class.
+		current = ""
+		if match := reFuncDecl.FindStringSubmatch(line); match != nil {
+			c.class(match[1]).Members[match[2]] = true
+		}
+	}
+}
+
+// has reports if the class name declares member, itself or through one of
+// its parents. parents returns the parents that the index does not know
+// of, such as those of the mixins of the source being annotated.
+func (c classIndex) has(name, member string, parents func(string) []string) bool {
+	seen := make(map[string]bool)
+	var walk func(string) bool
+	walk = func(name string) bool {
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		info, ok := c[name]
+		if ok && info.Members[member] {
+			return true
+		}
+		next := parents(name)
+		if ok {
+			next = slices.Concat(next, info.Parents)
+		}
+		for _, parent := range next {
+			if walk(parent) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(name)
+}
@@ -0,0 +1,265 @@
+package anno
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the name of the annotation sources config at the repo root.
+const ConfigFile = "annotations.yaml"
+
+// Source types. A git source is cloned, a dir source is copied and a
+// tarball source, a local file or an http(s) URL, is extracted.
+const (
+	TypeGit     = "git"
+	TypeDir     = "dir"
+	TypeTarball = "tarball"
+)
+
+// Post-processors that can be applied to the files of a source.
+const (
+	// ProcessMeta marks every Lua file as a ---@meta file.
+	ProcessMeta = "meta"
+	// ProcessMixins annotates the mixins in the Lua files with ---@class
+	// and generates the classes of the frames that use them.
+	ProcessMixins = "mixins"
+)
+
+var (
+	sourceTypes = []string{TypeGit, TypeDir, TypeTarball}
+	processors  = []string{ProcessMeta, ProcessMixins}
+	// reserved are the folders of annotations/ that are not written from a
+	// source.
+	reserved = []string{"generated", "manual"}
+)
+
+// Source is a source of third party annotations, copied to
+// annotations/<Name>.
+type Source struct {
+	Name string
+	// URL is a git URL, a local directory or a tarball. Local paths are
+	// relative to the repo root, or start with //.
+	URL string
+	// Type is the source type, inferred from URL when it is not set.
+	Type string
+	// Ref is the tag or branch of a git source. The default branch is used
+	// when it is empty.
+	Ref string
+	// SubDirs are the directories of the source to copy.
+	SubDirs []string
+	// Process lists the post-processors to apply.
+	Process []string
+	// GameVersion ties the ref of a git source to the game version of the
+	// retail "## Interface:" in Moonlight.toc, e.g. 11.1.5 for 110105. The
+	// ref is derived from it when the config does not pin one.
+	GameVersion bool
+	// line is the line of the source in the config file.
+	line int
+	// refNode is the ref value in the config file, nil when the config
+	// does not set one.
+	refNode *yaml.Node
+}
+
+// Config is the parsed annotations.yaml.
+type Config struct {
+	Sources []Source
+}
+
+// LoadConfig reads the annotation sources config at the repo root.
+func LoadConfig(root string) (*Config, error) {
+	content, err := os.ReadFile(filepath.Join(root, ConfigFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not exist, it lists the sources anno update fetches", ConfigFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ConfigFile, err)
+	}
+	return ParseConfig(content)
+}
+
+// configError is an error at a line of the config file.
+func configError(node *yaml.Node, format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", ConfigFile, node.Line, fmt.Sprintf(format, args...))
+}
+
+// ParseConfig parses the contents of annotations.yaml. Unknown keys are
+// errors, so that a typo never silently changes what is fetched.
+func ParseConfig(content []byte) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s: no sources are configured", ConfigFile)
+	}
+	top := doc.Content[0]
+	if top.Kind != yaml.MappingNode {
+		return nil, configError(top, "expected a mapping of keys to values")
+	}
+
+	cfg := &Config{}
+	for i := 0; i < len(top.Content); i += 2 {
+		key, value := top.Content[i], top.Content[i+1]
+		if key.Value != "sources" {
+			return nil, configError(key, "unknown key %q, the only supported key is sources", key.Value)
+		}
+		if value.Kind != yaml.SequenceNode {
+			return nil, configError(value, "sources must be a list")
+		}
+		for _, item := range value.Content {
+			source, err := parseSource(item)
+			if err != nil {
+				return nil, err
+			}
+			if slices.ContainsFunc(cfg.Sources, func(s Source) bool { return s.Name == source.Name }) {
+				return nil, configError(item, "source %s is listed more than once", source.Name)
+			}
+			cfg.Sources = append(cfg.Sources, source)
+		}
+	}
+	if len(cfg.Sources) == 0 {
+		return nil, configError(top, "no sources are configured")
+	}
+	return cfg, nil
+}
+
+func parseSource(node *yaml.Node) (Source, error) {
+	if node.Kind != yaml.MappingNode {
+		return Source{}, configError(node, "a source must be a mapping with name, url, ref, subdirs and process")
+	}
+	s := Source{line: node.Line}
+	seen := make(map[string]bool)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if seen[key.Value] {
+			return Source{}, configError(key, "%s is set more than once", key.Value)
+		}
+		seen[key.Value] = true
+
+		var err error
+		switch key.Value {
+		case "name":
+			s.Name, err = parseString(value, key.Value)
+		case "url":
+			s.URL, err = parseString(value, key.Value)
+		case "type":
+			s.Type, err = parseString(value, key.Value)
+			if err == nil && !slices.Contains(sourceTypes, s.Type) {
+				err = configError(value, "unknown type %q, expected one of %s", s.Type, strings.Join(sourceTypes, ", "))
+			}
+		case "ref":
+			s.Ref, err = parseString(value, key.Value)
+			s.refNode = value
+		case "game-version":
+			if value.Kind != yaml.ScalarNode || value.Decode(&s.GameVersion) != nil {
+				err = configError(value, "game-version must be yes or no")
+			}
+		case "subdirs":
+			s.SubDirs, err = parseList(value, key.Value)
+			for _, dir := range s.SubDirs {
+				clean := path.Clean(dir)
+				if err == nil && (path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../")) {
+					err = configError(value, "subdir %q must be a directory inside the source", dir)
+				}
+			}
+		case "process":
+			s.Process, err = parseList(value, key.Value)
+			for _, p := range s.Process {
+				if err == nil && !slices.Contains(processors, p) {
+					err = configError(value, "unknown post-processor %q, expected one of %s", p, strings.Join(processors, ", "))
+				}
+			}
+		default:
+			err = configError(key, "unknown source key %q, supported keys are name, url, type, ref, game-version, subdirs and process", key.Value)
+		}
+		if err != nil {
+			return Source{}, err
+		}
+	}
+
+	switch {
+	case s.Name == "":
+		return Source{}, configError(node, "source needs a name")
+	case strings.ContainsAny(s.Name, `/\`) || s.Name == "." || s.Name == "..":
+		return Source{}, configError(node, "source name %q must be a single folder name", s.Name)
+	case slices.Contains(reserved, s.Name):
+		return Source{}, configError(node, "source name %q is reserved", s.Name)
+	case s.URL == "":
+		return Source{}, configError(node, "source %s needs a url", s.Name)
+	case len(s.SubDirs) == 0:
+		return Source{}, configError(node, "source %s needs at least one subdir", s.Name)
+	case s.Type != "" && s.Type != TypeGit && s.Ref != "":
+		return Source{}, configError(node, "source %s is a %s, only git sources have a ref", s.Name, s.Type)
+	case s.Type != "" && s.Type != TypeGit && s.GameVersion:
+		return Source{}, configError(node, "source %s is a %s, only git sources can follow the game version", s.Name, s.Type)
+	}
+	return s, nil
+}
+
+func parseString(node *yaml.Node, key string) (string, error) {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return "", configError(node, "%s must be a string", key)
+	}
+	return node.Value, nil
+}
+
+func parseList(node *yaml.Node, key string) ([]string, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, configError(node, "%s must be a list", key)
+	}
+	var list []string
+	for _, item := range node.Content {
+		value, err := parseString(item, key+" entries")
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, strings.TrimSuffix(strings.ReplaceAll(value, `\`, "/"), "/"))
+	}
+	return list, nil
+}
+
+// Processes reports if the source applies the post-processor p.
+func (s Source) Processes(p string) bool {
+	return slices.Contains(s.Process, p)
+}
+
+// SetRef returns content, the config file that source was parsed from,
+// with the ref that source pins replaced by ref. The rest of the file,
+// including comments and the quoting style of the ref, is kept as it is.
+func SetRef(content []byte, source Source, ref string) []byte {
+	node := source.refNode
+	if node == nil {
+		return content
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	line := lines[node.Line-1]
+	start := node.Column - 1
+
+	var value string
+	end := len(line)
+	switch node.Style {
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		quote := line[start : start+1]
+		if i := strings.Index(line[start+1:], quote); i != -1 {
+			end = start + 1 + i + 1
+		}
+		value = quote + ref + quote
+	default:
+		if i := strings.IndexAny(line[start:], ",]}\r\n"); i != -1 {
+			end = start + i
+		}
+		if i := strings.Index(line[start:end], " #"); i != -1 {
+			end = start + i
+		}
+		end = start + len(strings.TrimRight(line[start:end], " \t"))
+		value = ref
+	}
+	lines[node.Line-1] = line[:start] + value + line[end:]
+	return []byte(strings.Join(lines, ""))
+}
@@ -0,0 +1,93 @@
+package anno
+
+import (
+	"testing"
+)
+
+func TestSetRef(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"plain",
+			"    ref: 11.1.5\n",
+			"    ref: 11.2.0\n",
+		},
+		{
+			"double quoted",
+			"    ref: \"11.1.5\"\n",
+			"    ref: \"11.2.0\"\n",
+		},
+		{
+			"single quoted with a comment",
+			"    ref: '11.1.5' # pinned\n",
+			"    ref: '11.2.0' # pinned\n",
+		},
+		{
+			"trailing comment",
+			"    ref: 11.1.5   # pinned\n",
+			"    ref: 11.2.0   # pinned\n",
+		},
+		{
+			"windows line ending",
+			"    ref: 11.1.5\r\n",
+			"    ref: 11.2.0\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "sources:\n  - name: ui\n    url: https://example.com/ui\n" + tt.source + "    subdirs:\n      - Interface\n"
+			cfg, err := ParseConfig([]byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(SetRef([]byte(content), cfg.Sources[0], "11.2.0"))
+			want := "sources:\n  - name: ui\n    url: https://example.com/ui\n" + tt.want + "    subdirs:\n      - Interface\n"
+			if got != want {
+				t.Errorf("SetRef() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSetRefFlowStyle(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{
+			"sources:\n  - {name: ui, url: https://example.com/ui, ref: 11.1.5, subdirs: [Interface]}\n",
+			"sources:\n  - {name: ui, url: https://example.com/ui, ref: 11.2.0, subdirs: [Interface]}\n",
+		},
+		{
+			"sources:\n  - {name: ui, url: https://example.com/ui, subdirs: [Interface], ref: 11.1.5}\n",
+			"sources:\n  - {name: ui, url: https://example.com/ui, subdirs: [Interface], ref: 11.2.0}\n",
+		},
+		{
+			"sources:\n  - {name: ui, url: https://example.com/ui, subdirs: [Interface], ref: \"11.1.5\"} # ui\n",
+			"sources:\n  - {name: ui, url: https://example.com/ui, subdirs: [Interface], ref: \"11.2.0\"} # ui\n",
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := ParseConfig([]byte(tt.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(SetRef([]byte(tt.content), cfg.Sources[0], "11.2.0")); got != tt.want {
+			t.Errorf("SetRef() =\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+}
+
+func TestSetRefWithoutRef(t *testing.T) {
+	content := "sources:\n  - name: ui\n    url: https://example.com/ui\n    subdirs:\n      - Interface\n"
+	cfg, err := ParseConfig([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(SetRef([]byte(content), cfg.Sources[0], "11.2.0")); got != content {
+		t.Errorf("SetRef() without a pinned ref =\n%s\nwant the config unchanged", got)
+	}
+}
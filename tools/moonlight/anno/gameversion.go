@@ -0,0 +1,113 @@
+package anno
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+)
+
+// tocFile is the TOC that declares the interface the addon is built for.
+const tocFile = "Moonlight.toc"
+
+// resolveGameVersion derives the ref of every game-version source that does
+// not pin one from the retail interface of Moonlight.toc, as staged in cs,
+// and warns loudly about every pinned ref that disagrees with it.
+func resolveGameVersion(cs *changeset.Set, reporoot string, cfg *Config) error {
+	var sources []*Source
+	for i := range cfg.Sources {
+		if cfg.Sources[i].GameVersion {
+			sources = append(sources, &cfg.Sources[i])
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	content, err := cs.Read(filepath.Join(reporoot, tocFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", tocFile, err)
+	}
+	iface, err := toc.Parse(content).Retail()
+	if err != nil {
+		return fmt.Errorf("%s: %w", tocFile, err)
+	}
+	want := toc.GameVersion(iface)
+
+	for _, source := range sources {
+		if source.refNode == nil {
+			source.Ref = want
+			continue
+		}
+		if source.Ref != want {
+			warnGameVersion(*source, iface)
+		}
+	}
+	return nil
+}
+
+// warnGameVersion warns that source is pinned to another game version than
+// the one the addon is built for.
+func warnGameVersion(source Source, iface int) {
+	lines := []string{
+		strings.Repeat("=", 72),
+		fmt.Sprintf("%s is pinned to %s in %s, but %s declares", source.Name, source.Ref, ConfigFile, tocFile),
+		fmt.Sprintf("## Interface: %d, which is game version %s. The annotations do not", iface, toc.GameVersion(iface)),
+		"match the game version the addon is built for. Update both with:",
+		"  moonlight anno update --game-version <version>",
+		strings.Repeat("=", 72),
+	}
+	for _, line := range lines {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", line)
+	}
+}
+
+// setGameVersion stages Moonlight.toc with its retail interface set to
+// version, and annotations.yaml with the pinned ref of every game-version
+// source set to match. It returns the updated config.
+func setGameVersion(cs *changeset.Set, reporoot string, cfg *Config, version string) (*Config, error) {
+	iface, err := toc.ParseGameVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	ref := toc.GameVersion(iface)
+	fmt.Printf("Setting the game version to %s, ## Interface: %d\n", ref, iface)
+
+	tocPath := filepath.Join(reporoot, tocFile)
+	f, err := toc.Load(tocPath)
+	if err != nil {
+		return nil, err
+	}
+	current := f.Bytes()
+	if err := f.SetRetail(iface); err != nil {
+		return nil, fmt.Errorf("%s: %w", tocFile, err)
+	}
+	if !bytes.Equal(f.Bytes(), current) {
+		cs.Write(tocPath, f.Bytes(), 0644)
+	}
+
+	configPath := filepath.Join(reporoot, ConfigFile)
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ConfigFile, err)
+	}
+	updated := content
+	tied := false
+	for _, source := range cfg.Sources {
+		if source.GameVersion {
+			updated = SetRef(updated, source, ref)
+			tied = true
+		}
+	}
+	if !tied {
+		return nil, fmt.Errorf("no source in %s sets game-version: yes", ConfigFile)
+	}
+	if !bytes.Equal(updated, content) {
+		cs.Write(configPath, updated, 0644)
+	}
+	return ParseConfig(updated)
+}
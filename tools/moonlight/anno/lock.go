@@ -0,0 +1,101 @@
+package anno
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// LockFile is the name of the lock file at the repo root, which records
+// the version of every source that anno update last fetched.
+const LockFile = "annotations.lock"
+
+// LockEntry is the locked version of a source, along with the config it
+// was fetched with.
+type LockEntry struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Type    string   `json:"type"`
+	Ref     string   `json:"ref,omitempty"`
+	SubDirs []string `json:"subdirs"`
+	Process []string `json:"process,omitempty"`
+	// Version is the commit hash of a git source, or the SHA-256 digest of
+	// the files of a dir or tarball source.
+	Version string `json:"version"`
+}
+
+// Lock is the parsed annotations.lock.
+type Lock struct {
+	Sources []LockEntry `json:"sources"`
+}
+
+// LoadLock reads the lock file at the repo root, or returns an empty lock
+// if there is none.
+func LoadLock(root string) (*Lock, error) {
+	content, err := os.ReadFile(filepath.Join(root, LockFile))
+	if os.IsNotExist(err) {
+		return &Lock{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LockFile, err)
+	}
+	lock := &Lock{}
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	return lock, nil
+}
+
+// NewLockEntry locks source, of the given type, at version.
+func NewLockEntry(source Source, kind, version string) LockEntry {
+	return LockEntry{
+		Name:    source.Name,
+		URL:     source.URL,
+		Type:    kind,
+		Ref:     source.Ref,
+		SubDirs: source.SubDirs,
+		Process: source.Process,
+		Version: version,
+	}
+}
+
+// Entry returns the entry of the named source.
+func (l *Lock) Entry(name string) (LockEntry, bool) {
+	i := slices.IndexFunc(l.Sources, func(e LockEntry) bool { return e.Name == name })
+	if i == -1 {
+		return LockEntry{}, false
+	}
+	return l.Sources[i], true
+}
+
+// Bytes encodes the lock file.
+func (l *Lock) Bytes() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", LockFile, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Matches reports if the entry was fetched with the same config as source
+// has now, so that its version still describes the source. An inferred
+// type is not compared, as it can only be inferred while a local source
+// exists.
+func (e LockEntry) Matches(source Source) bool {
+	return e.Name == source.Name &&
+		e.URL == source.URL &&
+		(source.Type == "" || e.Type == source.Type) &&
+		e.Ref == source.Ref &&
+		slices.Equal(e.SubDirs, source.SubDirs) &&
+		slices.Equal(e.Process, source.Process)
+}
+
+// ShortVersion returns the abbreviated form of a locked version.
+func ShortVersion(version string) string {
+	if len(version) > 12 {
+		return version[:12]
+	}
+	return version
+}
@@ -0,0 +1,244 @@
+package anno
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// annoRepo is a repo with a dir source and a tarball source, both of which
+// can be updated offline.
+type annoRepo struct {
+	root string
+}
+
+func newAnnoRepo(t *testing.T) *annoRepo {
+	t.Helper()
+	r := &annoRepo{root: t.TempDir()}
+	r.write(t, "go.work", "go 1.24\n")
+	r.write(t, ConfigFile, `sources:
+  - name: mirror
+    url: mirror
+    subdirs: [Annotations]
+    process: [meta]
+  - name: archive
+    url: archive.tar.gz
+    subdirs: [Core]
+`)
+	r.write(t, "mirror/Annotations/api.lua", "function C_Test.Get() end\n")
+	r.write(t, "mirror/README.md", "not annotations\n")
+	r.writeTarball(t, map[string]string{"Core/core.lua": "---@meta\nlocal x\n"})
+	t.Chdir(r.root)
+	return r
+}
+
+func (r *annoRepo) path(rel string) string {
+	return filepath.Join(r.root, filepath.FromSlash(rel))
+}
+
+func (r *annoRepo) write(t *testing.T, rel, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(r.path(rel)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(r.path(rel), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (r *annoRepo) read(t *testing.T, rel string) string {
+	t.Helper()
+	content, err := os.ReadFile(r.path(rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// writeTarball writes archive.tar.gz with files inside a single top level
+// directory, like the archives GitHub serves.
+func (r *annoRepo) writeTarball(t *testing.T, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(r.path("archive.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: "archive-main/" + name, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func update(args ...string) error {
+	cmd := newUpdateCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(&strings.Builder{})
+	cmd.SetErr(&strings.Builder{})
+	return cmd.Execute()
+}
+
+func TestUpdateLock(t *testing.T) {
+	r := newAnnoRepo(t)
+	if err := update(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.read(t, "annotations/mirror/Annotations/api.lua"); got != "---@meta\nfunction C_Test.Get() end\n" {
+		t.Errorf("api.lua = %q, want it marked as meta", got)
+	}
+	if got := r.read(t, "annotations/archive/Core/core.lua"); got != "---@meta\nlocal x\n" {
+		t.Errorf("core.lua = %q, want the file from the tarball", got)
+	}
+	lock, err := LoadLock(r.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, kind := range map[string]string{"mirror": TypeDir, "archive": TypeTarball} {
+		entry, ok := lock.Entry(name)
+		if !ok || entry.Type != kind || len(entry.Version) != 64 {
+			t.Errorf("lock entry of %s = %+v, want a %s locked at a digest", name, entry, kind)
+		}
+	}
+	locked := r.read(t, LockFile)
+
+	// A source that matches its lock entry is skipped, so a local edit
+	// survives and the lock stays as it is.
+	r.write(t, "annotations/mirror/Annotations/api.lua", "edited\n")
+	if err := update(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.read(t, "annotations/mirror/Annotations/api.lua"); got != "edited\n" {
+		t.Errorf("api.lua = %q, want the unchanged source skipped", got)
+	}
+	if got := r.read(t, LockFile); got != locked {
+		t.Errorf("lock after an update without changes =\n%s\nwant\n%s", got, locked)
+	}
+
+	// A file outside the subdirs does not change the version.
+	r.write(t, "mirror/README.md", "changed\n")
+	if err := update("--check"); err != nil {
+		t.Errorf("anno update --check = %v, want no drift", err)
+	}
+
+	// A changed source is fetched again.
+	r.write(t, "mirror/Annotations/api.lua", "function C_Test.Set() end\n")
+	if err := update(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.read(t, "annotations/mirror/Annotations/api.lua"); got != "---@meta\nfunction C_Test.Set() end\n" {
+		t.Errorf("api.lua = %q, want the changed source fetched", got)
+	}
+	if r.read(t, LockFile) == locked {
+		t.Error("lock unchanged after the source changed")
+	}
+}
+
+func TestUpdateCheck(t *testing.T) {
+	r := newAnnoRepo(t)
+	if err := update("--check"); !errors.Is(err, ErrDrift) {
+		t.Errorf("anno update --check without a lock = %v, want ErrDrift", err)
+	}
+	if err := update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := update("--check"); err != nil {
+		t.Errorf("anno update --check = %v, want no drift", err)
+	}
+
+	r.write(t, "mirror/Annotations/new.lua", "local y\n")
+	locked := r.read(t, LockFile)
+	if err := update("--check"); !errors.Is(err, ErrDrift) {
+		t.Errorf("anno update --check after a change = %v, want ErrDrift", err)
+	}
+	if _, err := os.Stat(r.path("annotations/mirror/Annotations/new.lua")); !os.IsNotExist(err) {
+		t.Error("anno update --check fetched the changed source")
+	}
+	if got := r.read(t, LockFile); got != locked {
+		t.Error("anno update --check changed the lock")
+	}
+}
+
+func TestUpdateFrozen(t *testing.T) {
+	r := newAnnoRepo(t)
+	if err := update("--frozen"); err == nil {
+		t.Error("anno update --frozen without a lock succeeded, want an error")
+	}
+	if err := update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The locked versions are fetched again, even when they are installed.
+	r.write(t, "annotations/archive/Core/core.lua", "edited\n")
+	if err := update("--frozen"); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.read(t, "annotations/archive/Core/core.lua"); got != "---@meta\nlocal x\n" {
+		t.Errorf("core.lua = %q, want the locked version restored", got)
+	}
+
+	// A dir that changed since it was locked can not be reproduced.
+	r.write(t, "mirror/Annotations/api.lua", "changed\n")
+	locked := r.read(t, LockFile)
+	err := update("--frozen")
+	if err == nil || !strings.Contains(err.Error(), "can not be reproduced") {
+		t.Errorf("anno update --frozen after a change = %v, want an error that it can not be reproduced", err)
+	}
+	if got := r.read(t, "annotations/mirror/Annotations/api.lua"); got != "---@meta\nfunction C_Test.Get() end\n" {
+		t.Errorf("api.lua = %q, want a failed update to change nothing", got)
+	}
+	if got := r.read(t, LockFile); got != locked {
+		t.Error("anno update --frozen changed the lock")
+	}
+}
+
+func TestLockEntryMatches(t *testing.T) {
+	source := Source{
+		Name:    "ui",
+		URL:     "https://example.com/ui",
+		Ref:     "11.2.0",
+		SubDirs: []string{"Interface"},
+		Process: []string{"meta", "mixins"},
+	}
+	entry := NewLockEntry(source, TypeGit, "abc")
+	tests := []struct {
+		name   string
+		change func(s *Source)
+		want   bool
+	}{
+		{"unchanged", func(s *Source) {}, true},
+		{"explicit type", func(s *Source) { s.Type = TypeGit }, true},
+		{"other type", func(s *Source) { s.Type = TypeDir }, false},
+		{"url", func(s *Source) { s.URL = "https://example.com/fork" }, false},
+		{"ref", func(s *Source) { s.Ref = "11.2.5" }, false},
+		{"subdirs", func(s *Source) { s.SubDirs = []string{"Interface", "Extra"} }, false},
+		{"process", func(s *Source) { s.Process = []string{"meta"} }, false},
+		{"game version", func(s *Source) { s.GameVersion = true }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := source
+			s.SubDirs = append([]string(nil), source.SubDirs...)
+			tt.change(&s)
+			if got := entry.Matches(s); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package anno
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/lua"
+)
+
+// mixinDecl is a table declared by a top level statement, the way Blizzard
+// declares mixins: X = {}, X = CreateFromMixins(A, B) or
+// X = Mixin({}, A, B), with or without local.
+type mixinDecl struct {
+	Name    string
+	Parents []string
+	// Local is true for a local declaration, and Literal for a plain table
+	// literal, which most files use for private tables rather than mixins.
+	Local   bool
+	Literal bool
+	// Pos is the start of the statement.
+	Pos lua.Pos
+	// Class is true when the comments right above the statement declare a
+	// class, and Fields holds the fields they declare.
+	Class  bool
+	Fields []string
+}
+
+// methodDecl is a method defined on a table by a top level
+// function X:Method() statement.
+type methodDecl struct {
+	Class string
+	Name  string
+	// Params are the names of the parameters, with ... for a vararg.
+	Params []string
+	// Fields are the fields that the method assigns to self.
+	Fields []string
+	// Pos is the start of the statement.
+	Pos lua.Pos
+	// Documented is true when the comments right above the statement
+	// annotate the parameters.
+	Documented bool
+}
+
+// luaFile is what a Lua file declares about its mixins.
+type luaFile struct {
+	Path    string
+	Mixins  []mixinDecl
+	Methods []methodDecl
+}
+
+// parseLuaFile parses src, the content of the Lua file at path.
+func parseLuaFile(path string, src []byte) (luaFile, error) {
+	chunk, err := lua.Parse(src)
+	if err != nil {
+		return luaFile{}, err
+	}
+	return luaFile{Path: path, Mixins: findMixins(chunk), Methods: findMethods(chunk)}, nil
+}
+
+// findMixins returns the mixins declared by the top level statements of
+// chunk, in source order. Only statements that declare a single name are
+// considered, as an annotation can only describe one.
+func findMixins(chunk *lua.Chunk) []mixinDecl {
+	var mixins []mixinDecl
+	for _, stmt := range chunk.Block.Stmts {
+		var target lua.Expr
+		var values []lua.Expr
+		local := false
+		switch s := stmt.(type) {
+		case *lua.LocalAssign:
+			if len(s.Names) == 1 {
+				target, values, local = s.Names[0], s.Values, true
+			}
+		case *lua.Assign:
+			if len(s.Targets) == 1 {
+				target, values = s.Targets[0], s.Values
+			}
+		}
+		ident, ok := target.(*lua.Ident)
+		if !ok || len(values) != 1 {
+			continue
+		}
+		parents, ok := mixinParents(values[0])
+		if !ok {
+			continue
+		}
+		_, literal := values[0].(*lua.Table)
+		m := mixinDecl{Name: ident.Name, Parents: parents, Local: local, Literal: literal, Pos: stmt.Pos()}
+		for _, c := range stmt.Doc() {
+			text := strings.TrimSpace(c.Text)
+			if strings.HasPrefix(text, "---@class") {
+				m.Class = true
+			}
+			if match := reFieldDecl.FindStringSubmatch(text); match != nil {
+				m.Fields = append(m.Fields, match[1])
+			}
+		}
+		mixins = append(mixins, m)
+	}
+	return mixins
+}
+
+// mixinParents reports if value creates a mixin, and returns the mixins it
+// inherits from. Arguments that are not names, such as calls, are not
+// parents that can be named and are left out.
+func mixinParents(value lua.Expr) ([]string, bool) {
+	switch v := value.(type) {
+	case *lua.Table:
+		return nil, true
+	case *lua.Call:
+		fn, ok := v.Func.(*lua.Ident)
+		if !ok {
+			return nil, false
+		}
+		var args []lua.Expr
+		switch fn.Name {
+		case "CreateFromMixins":
+			args = v.Args
+		case "Mixin":
+			// Mixin copies the other arguments into the first one, which is
+			// a new table or an object that is a parent itself.
+			if len(v.Args) == 0 {
+				return nil, false
+			}
+			args = v.Args
+			if _, ok := v.Args[0].(*lua.Table); ok {
+				args = v.Args[1:]
+			}
+		default:
+			return nil, false
+		}
+		var parents []string
+		for _, arg := range args {
+			for {
+				paren, ok := arg.(*lua.Paren)
+				if !ok {
+					break
+				}
+				arg = paren.X
+			}
+			if name, ok := lua.DottedName(arg); ok && !slices.Contains(parents, name) {
+				parents = append(parents, name)
+			}
+		}
+		return parents, true
+	}
+	return nil, false
+}
+
+// findMethods returns the methods defined by the top level statements of
+// chunk on a name, such as function XMixin:OnLoad(), in source order.
+func findMethods(chunk *lua.Chunk) []methodDecl {
+	var methods []methodDecl
+	for _, stmt := range chunk.Block.Stmts {
+		fn, ok := stmt.(*lua.FunctionStmt)
+		if !ok || fn.Name.Method == nil || len(fn.Name.Path) != 1 {
+			continue
+		}
+		m := methodDecl{
+			Class:  fn.Name.Path[0].Name,
+			Name:   fn.Name.Method.Name,
+			Fields: selfFields(fn.Func.Body),
+			Pos:    stmt.Pos(),
+		}
+		for _, param := range fn.Func.Params {
+			m.Params = append(m.Params, param.Name)
+		}
+		if fn.Func.Vararg {
+			m.Params = append(m.Params, "...")
+		}
+		for _, c := range stmt.Doc() {
+			if strings.HasPrefix(strings.TrimSpace(c.Text), "---@param") {
+				m.Documented = true
+			}
+		}
+		methods = append(methods, m)
+	}
+	return methods
+}
+
+// selfFields returns the names that body assigns to self.name, in source
+// order.
+func selfFields(body *lua.Block) []string {
+	var fields []string
+	lua.Inspect(body, func(n lua.Node) bool {
+		switch n := n.(type) {
+		case *lua.FunctionStmt:
+			// A method defined in the body has a self of its own.
+			return n.Name.Method == nil
+		case *lua.Function:
+			// So does a function that takes a self parameter.
+			return !slices.ContainsFunc(n.Params, func(p *lua.Ident) bool { return p.Name == "self" })
+		case *lua.Assign:
+			for _, target := range n.Targets {
+				field, ok := target.(*lua.Field)
+				if !ok {
+					continue
+				}
+				if x, ok := field.X.(*lua.Ident); ok && x.Name == "self" && !slices.Contains(fields, field.Name.Name) {
+					fields = append(fields, field.Name.Name)
+				}
+			}
+		}
+		return true
+	})
+	return fields
+}
+
+// mixinSet is what the Lua files of a source declare about their mixins.
+type mixinSet struct {
+	decls map[string]mixinDecl
+	// methods and fields hold the members that the methods of a mixin
+	// define, by mixin name.
+	methods map[string]map[string]bool
+	fields  map[string]map[string]bool
+	// stubs holds the methods of a mixin that take parameters without
+	// annotating them, by mixin name, in source order.
+	stubs map[string][]methodDecl
+	// used holds the names that are used as mixins.
+	used map[string]bool
+}
+
+// newMixinSet collects the mixins of files, and the members of the
+// mixins. xmlMixins are the names that XML files use in mixin attributes.
+// A local table literal is only a mixin when its name is used as one: by a
+// method definition, as a parent of another mixin or in xmlMixins. A mixin
+// declared more than once is described by the first declaration.
+func newMixinSet(files []luaFile, xmlMixins []string) *mixinSet {
+	s := &mixinSet{
+		decls:   make(map[string]mixinDecl),
+		methods: make(map[string]map[string]bool),
+		fields:  make(map[string]map[string]bool),
+		stubs:   make(map[string][]methodDecl),
+		used:    make(map[string]bool),
+	}
+	for _, name := range xmlMixins {
+		s.used[name] = true
+	}
+	for _, file := range files {
+		for _, m := range file.Methods {
+			s.used[m.Class] = true
+		}
+		for _, m := range file.Mixins {
+			for _, parent := range m.Parents {
+				s.used[parent] = true
+			}
+		}
+	}
+	for _, file := range files {
+		for _, m := range file.Mixins {
+			if _, ok := s.decls[m.Name]; !ok && s.isMixin(m) {
+				s.decls[m.Name] = m
+			}
+		}
+	}
+	for _, file := range files {
+		for _, m := range file.Methods {
+			if _, ok := s.decls[m.Class]; !ok {
+				continue
+			}
+			if s.methods[m.Class] == nil {
+				s.methods[m.Class] = make(map[string]bool)
+				s.fields[m.Class] = make(map[string]bool)
+			}
+			if !s.methods[m.Class][m.Name] && !m.Documented && len(m.Params) > 0 {
+				s.stubs[m.Class] = append(s.stubs[m.Class], m)
+			}
+			s.methods[m.Class][m.Name] = true
+			for _, field := range m.Fields {
+				s.fields[m.Class][field] = true
+			}
+		}
+	}
+	return s
+}
+
+// isMixin reports if the declaration m is a mixin rather than a private
+// table of its file.
+func (s *mixinSet) isMixin(m mixinDecl) bool {
+	return !m.Local || !m.Literal || s.used[m.Name]
+}
+
+// parents returns the parents of the mixin name.
+func (s *mixinSet) parents(name string) []string {
+	return s.decls[name].Parents
+}
+
+// has reports if the mixin name annotates member as a field or defines it
+// as a method, itself or through one of the mixins it inherits from.
+func (s *mixinSet) has(name, member string) bool {
+	seen := make(map[string]bool)
+	var walk func(string) bool
+	walk = func(name string) bool {
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		if s.methods[name][member] || slices.Contains(s.decls[name].Fields, member) {
+			return true
+		}
+		for _, parent := range s.parents(name) {
+			if walk(parent) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(name)
+}
+
+// insertion is text to insert at an offset of a source, in place of the
+// cut bytes that follow the offset.
+type insertion struct {
+	offset int
+	cut    int
+	text   string
+}
+
+// above returns the insertion of lines right above the statement at pos,
+// with the indentation of the statement.
+func above(src []byte, pos lua.Pos, lines []string) insertion {
+	lineStart := bytes.LastIndexByte(src[:pos.Offset], '\n') + 1
+	indent := string(src[lineStart:pos.Offset])
+	if strings.TrimSpace(indent) != "" {
+		// The statement follows other code on its line, so it is moved to
+		// a line of its own below the annotations.
+		trimmed := strings.TrimRight(indent, " \t")
+		return insertion{lineStart + len(trimmed), len(indent) - len(trimmed), "\n" + strings.Join(lines, "\n") + "\n"}
+	}
+	var text strings.Builder
+	for _, line := range lines {
+		text.WriteString(indent + line + "\n")
+	}
+	return insertion{lineStart, 0, text.String()}
+}
+
+// annotate returns src, the content of file, with a ---@class annotation
+// above every mixin that has none. Classes declared by known are left
+// alone. It returns the number of mixins annotated.
+func (s *mixinSet) annotate(src []byte, file luaFile, known classIndex) ([]byte, int) {
+	var inserts []insertion
+	for _, m := range file.Mixins {
+		if m.Class || !s.isMixin(m) || known.declared(m.Name) {
+			continue
+		}
+		inserts = append(inserts, above(src, m.Pos, []string{classAnnotation(m)}))
+	}
+	if len(inserts) == 0 {
+		return src, 0
+	}
+
+	sort.SliceStable(inserts, func(i, j int) bool { return inserts[i].offset < inserts[j].offset })
+	var out bytes.Buffer
+	prev := 0
+	for _, ins := range inserts {
+		out.Write(src[prev:ins.offset])
+		out.WriteString(ins.text)
+		prev = ins.offset + ins.cut
+	}
+	out.Write(src[prev:])
+	return out.Bytes(), len(inserts)
+}
+
+// classAnnotation returns the ---@class line of a mixin.
+func classAnnotation(m mixinDecl) string {
+	if len(m.Parents) == 0 {
+		return fmt.Sprintf("---@class %s", m.Name)
+	}
+	return fmt.Sprintf("---@class %s: %s", m.Name, strings.Join(m.Parents, ", "))
+}
+
+// memberAnnotations returns the stubs of the members of the mixins, for
+// the generated meta file. Every mixin gets a partial class with a
+// ---@field stub for every field that its methods assign to self, and a
+// stub of every method that does not annotate its parameters, with a
+// ---@param stub for every parameter. Members that the mixin or its parents
+// annotate or define already, or that known declares on them, are left
+// out, as they have a type already.
+func (s *mixinSet) memberAnnotations(known classIndex) string {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		var fields []string
+		for field := range s.fields[name] {
+			if s.has(name, field) || known.has(name, field, s.parents) {
+				continue
+			}
+			fields = append(fields, field)
+		}
+		var methods []methodDecl
+		for _, m := range s.stubs[name] {
+			if !known.has(name, m.Name, s.parents) {
+				methods = append(methods, m)
+			}
+		}
+		if len(fields) == 0 && len(methods) == 0 {
+			continue
+		}
+
+		sort.Strings(fields)
+		fmt.Fprintf(&b, "---@class %s\n", name)
+		for _, field := range fields {
+			fmt.Fprintf(&b, "---@field %s any\n", field)
+		}
+		// The class is bound to a local, so that the methods below extend
+		// it without declaring a global for a local mixin.
+		fmt.Fprintf(&b, "local %s = {}\n\n", name)
+		for _, m := range methods {
+			for _, param := range m.Params {
+				fmt.Fprintf(&b, "---@param %s any\n", param)
+			}
+			fmt.Fprintf(&b, "function %s:%s(%s) end\n\n", name, m.Name, strings.Join(m.Params, ", "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package anno
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, path, src string) luaFile {
+	t.Helper()
+	file, err := parseLuaFile(path, []byte(src))
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	return file
+}
+
+func TestFindMixins(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want is the mixins found, as name, parents and flags.
+		want []string
+	}{
+		{"global table", "FooMixin = {}", []string{"FooMixin [] global literal"}},
+		{"local table", "local cache = {}", []string{"cache [] local literal"}},
+		{"create from mixins", "X = CreateFromMixins(A, B.C, (D), E())", []string{"X [A B.C D] global"}},
+		{"call across lines", "X = CreateFromMixins(\n\tA, -- base\n\tB\n)", []string{"X [A B] global"}},
+		{"mixin into a new table", "local X = Mixin({}, A, A)", []string{"X [A] local"}},
+		{"mixin into a parent", "X = Mixin(Base, A)", []string{"X [Base A] global"}},
+		{"annotated", "---@class X\n---@field a number\n---@field private b string\nX = {}", []string{"X [] global literal class a,b"}},
+		{"detached annotation", "---@class X\n\nX = {}", []string{"X [] global literal"}},
+		{"not top level", "if x then X = {} end", nil},
+		{"several names", "A, B = {}, {}", nil},
+		{"field target", "a.B = {}", nil},
+		{"other call", "X = setmetatable({}, mt)", nil},
+		{"empty mixin call", "X = Mixin()", nil},
+		{"not a table", "X = 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range mustParse(t, "test.lua", tt.src).Mixins {
+				s := fmt.Sprintf("%s %v", m.Name, m.Parents)
+				if m.Local {
+					s += " local"
+				} else {
+					s += " global"
+				}
+				if m.Literal {
+					s += " literal"
+				}
+				if m.Class {
+					s += " class"
+				}
+				if len(m.Fields) > 0 {
+					s += " " + strings.Join(m.Fields, ",")
+				}
+				got = append(got, s)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mixins = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMethods(t *testing.T) {
+	file := mustParse(t, "test.lua", `
+function XMixin:OnLoad(a, ...)
+	self.a, self.b = a, 1
+	self.a = 2
+	self.c.d = 3
+	local f = function(self) self.notMine = 1 end
+	C_Timer.After(0, function() self.timer = 1 end)
+	function self:Inner() self.inner = 1 end
+end
+
+---@param force boolean
+function XMixin:Refresh(force) end
+
+function a.b:c() end
+function XMixin.Static(x) end
+`)
+	var got []string
+	for _, m := range file.Methods {
+		got = append(got, fmt.Sprintf("%s:%s %v %v %v", m.Class, m.Name, m.Params, m.Fields, m.Documented))
+	}
+	want := []string{
+		"XMixin:OnLoad [a ...] [a b timer] false",
+		"XMixin:Refresh [force] [] true",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("methods = %q, want %q", got, want)
+	}
+}
+
+func TestMixinSet(t *testing.T) {
+	files := []luaFile{
+		mustParse(t, "a.lua", `
+local cache = {}
+local UsedMixin = {}
+local XMLMixin = {}
+local BaseMixin = {}
+DerivedMixin = CreateFromMixins(BaseMixin)
+GlobalMixin = {}
+function UsedMixin:Get() return cache end
+`),
+		mustParse(t, "b.lua", "local pending = {}\nlocal GlobalMixin = Mixin({}, Other)"),
+	}
+	s := newMixinSet(files, []string{"XMLMixin"})
+	var got []string
+	for name := range s.decls {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	want := []string{"BaseMixin", "DerivedMixin", "GlobalMixin", "UsedMixin", "XMLMixin"}
+	if !slices.Equal(got, want) {
+		t.Errorf("mixins = %q, want %q", got, want)
+	}
+	// The first declaration describes a mixin declared twice.
+	if parents := s.parents("GlobalMixin"); len(parents) != 0 {
+		t.Errorf("GlobalMixin parents = %q, want none", parents)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	src := `local cache = {}
+FooMixin = CreateFromMixins(BaseMixin)
+
+---@class DoneMixin
+DoneMixin = {}
+
+local a = 1; InlineMixin = {}
+
+	BarMixin = CreateFromMixins(FooMixin,
+		BaseMixin)
+
+function FooMixin:OnLoad(a, ...)
+end
+
+---@param v number
+function FooMixin:Set(v) end
+
+function FooMixin:Typed(id) end
+
+function FooMixin:NoParams() end
+
+function NotAMixin:Method(a) end
+`
+	file := mustParse(t, "foo.lua", src)
+	known := make(classIndex)
+	known.scan("---@class KnownMixin\n---@class BaseMixin\n")
+	s := newMixinSet([]luaFile{file}, nil)
+
+	got, n := s.annotate([]byte(src), file, known)
+	want := `local cache = {}
+---@class FooMixin: BaseMixin
+FooMixin = CreateFromMixins(BaseMixin)
+
+---@class DoneMixin
+DoneMixin = {}
+
+local a = 1;
+---@class InlineMixin
+InlineMixin = {}
+
+	---@class BarMixin: FooMixin, BaseMixin
+	BarMixin = CreateFromMixins(FooMixin,
+		BaseMixin)
+
+function FooMixin:OnLoad(a, ...)
+end
+
+---@param v number
+function FooMixin:Set(v) end
+
+function FooMixin:Typed(id) end
+
+function FooMixin:NoParams() end
+
+function NotAMixin:Method(a) end
+`
+	if string(got) != want {
+		t.Errorf("annotate() =\n%s\nwant\n%s", got, want)
+	}
+	if n != 3 {
+		t.Errorf("annotated %d mixins, want 3", n)
+	}
+
+	// A mixin that known declares is left alone.
+	known.scan("---@class FooMixin\n---@class BarMixin\n---@class InlineMixin\n")
+	file = mustParse(t, "foo.lua", "FooMixin = {}\nfunction FooMixin:Typed(id) end\n")
+	if got, n := s.annotate([]byte("FooMixin = {}\nfunction FooMixin:Typed(id) end\n"), file, known); n != 0 {
+		t.Errorf("annotate() of known classes =\n%s\nwant no change", got)
+	}
+}
+
+func TestMemberAnnotations(t *testing.T) {
+	files := []luaFile{
+		mustParse(t, "a.lua", `
+---@class AMixin
+---@field typed number
+AMixin = {}
+BMixin = CreateFromMixins(AMixin, KnownMixin)
+local EmptyMixin = {}
+
+function AMixin:OnLoad(a, ...)
+	self.typed = 1
+	self.zeta, self.alpha = 1, 2
+	self.Refresh = nil
+end
+
+function AMixin:Refresh() end
+
+---@param force boolean
+function AMixin:Set(force) end
+
+function BMixin:OnLoad()
+	self.typed = 2
+	self.Refresh = nil
+	self.known = 3
+	self.inherited = 4
+	self.own = 5
+end
+
+function BMixin:Typed(id) end
+
+function BMixin:Untyped(id, name) end
+
+function EmptyMixin:OnLoad()
+end
+
+function NotAMixin:OnLoad(a)
+	self.ignored = 1
+end
+`),
+	}
+	known := make(classIndex)
+	known.scan(`---@class KnownMixin: GrandMixin
+---@field known number
+---@class GrandMixin
+---@field inherited string
+function GrandMixin:Typed(id) end
+`)
+	got := newMixinSet(files, nil).memberAnnotations(known)
+	want := `---@class AMixin
+---@field alpha any
+---@field zeta any
+local AMixin = {}
+
+---@param a any
+---@param ... any
+function AMixin:OnLoad(a, ...) end
+
+---@class BMixin
+---@field own any
+local BMixin = {}
+
+---@param id any
+---@param name any
+function BMixin:Untyped(id, name) end
+
+`
+	if got != want {
+		t.Errorf("memberAnnotations() =\n%s\nwant\n%s", got, want)
+	}
+}
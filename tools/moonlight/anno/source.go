@@ -0,0 +1,258 @@
+package anno
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+)
+
+// isRemote reports if url is a network location rather than a local path.
+func isRemote(url string) bool {
+	return strings.Contains(url, "://") || strings.HasPrefix(url, "git@")
+}
+
+// Location returns the URL of the source, with local paths made absolute
+// against the repo root.
+func (s Source) Location(root string) string {
+	if isRemote(s.URL) {
+		return s.URL
+	}
+	if rest, ok := strings.CutPrefix(s.URL, "//"); ok {
+		return filepath.Join(root, rest)
+	}
+	if filepath.IsAbs(s.URL) {
+		return s.URL
+	}
+	return filepath.Join(root, s.URL)
+}
+
+// Kind returns the type of the source. Without an explicit type, a URL
+// ending in .tar, .tar.gz or .tgz is a tarball, a local directory that is
+// not a git repository is a dir, and anything else is a git repository.
+func (s Source) Kind(root string) string {
+	if s.Type != "" {
+		return s.Type
+	}
+	lower := strings.ToLower(s.URL)
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return TypeTarball
+		}
+	}
+	if isRemote(s.URL) {
+		return TypeGit
+	}
+	loc := s.Location(root)
+	for _, marker := range []string{".git", "HEAD"} {
+		if _, err := os.Stat(filepath.Join(loc, marker)); err == nil {
+			return TypeGit
+		}
+	}
+	return TypeDir
+}
+
+// Fetch places the files of the source in dir, which must be empty, and
+// returns the version it fetched: the commit of a git source, or the
+// digest of the subdirs of a dir or tarball source. With a pin, that
+// version is fetched; a dir or tarball that changed since can not be
+// fetched at its pin, which is an error.
+func (s Source) Fetch(root, dir, pin string) (string, error) {
+	loc := s.Location(root)
+	kind := s.Kind(root)
+	if kind == TypeGit {
+		return fetchGit(loc, s.Ref, pin, s.SubDirs, dir)
+	}
+	if s.Ref != "" {
+		return "", fmt.Errorf("%s is a %s, only git sources have a ref", loc, kind)
+	}
+
+	switch kind {
+	case TypeDir:
+		info, err := os.Stat(loc)
+		if err != nil || !info.IsDir() {
+			return "", fmt.Errorf("source directory %s does not exist", loc)
+		}
+		if err := util.CopyDir(loc, dir); err != nil {
+			return "", fmt.Errorf("failed to copy %s: %w", loc, err)
+		}
+	case TypeTarball:
+		if err := fetchTarball(loc, dir); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown source type %q", kind)
+	}
+
+	version, err := digest(dir, s.SubDirs)
+	if err != nil {
+		return "", err
+	}
+	if pin != "" && version != pin {
+		return "", fmt.Errorf("%s changed since it was locked at %s and can not be reproduced", loc, ShortVersion(pin))
+	}
+	return version, nil
+}
+
+// Latest returns the version Fetch would fetch now. For a git source only
+// the ref is looked up; a dir or tarball source is fetched to digest it.
+func (s Source) Latest(root string) (string, error) {
+	if s.Kind(root) == TypeGit {
+		return latestGit(s.Location(root), s.Ref)
+	}
+	dir, err := os.MkdirTemp("", "moonlight-source-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	return s.Fetch(root, dir, "")
+}
+
+// digest returns the SHA-256 digest of the paths and contents of every
+// file in subdirs of dir.
+func digest(dir string, subdirs []string) (string, error) {
+	h := sha256.New()
+	for _, subdir := range subdirs {
+		base := filepath.Join(dir, filepath.FromSlash(subdir))
+		err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(content))
+			h.Write(content)
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("subdir %s does not exist in the source", subdir)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fetchTarball extracts the tarball at loc, a local file or an http(s)
+// URL, into dir. Gzip compression is detected from the content. When
+// every entry is inside a single top level directory, as in the archives
+// GitHub serves, that directory is stripped.
+func fetchTarball(loc, dir string) error {
+	var r io.ReadCloser
+	if isRemote(loc) {
+		resp, err := http.Get(loc)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", loc, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("failed to download %s: %s", loc, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(loc)
+		if err != nil {
+			return fmt.Errorf("failed to open tarball: %w", err)
+		}
+		r = f
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(r)
+	var tr *tar.Reader
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", loc, err)
+		}
+		defer gz.Close()
+		tr = tar.NewReader(gz)
+	} else {
+		tr = tar.NewReader(br)
+	}
+
+	// Extract next to dir, so that the top level directory can be moved
+	// into place whatever its entries are named.
+	extracted, err := os.MkdirTemp(filepath.Dir(dir), "tarball-")
+	if err != nil {
+		return fmt.Errorf("failed to create extraction dir: %w", err)
+	}
+	defer os.RemoveAll(extracted)
+	prefix := ""
+	single := true
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", loc, err)
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." || hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("%s: entry %s is outside of the archive", loc, hdr.Name)
+		}
+		top, _, _ := strings.Cut(name, "/")
+		if prefix == "" {
+			prefix = top
+		} else if top != prefix {
+			single = false
+		}
+
+		target := filepath.Join(extracted, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+			}
+		}
+	}
+
+	src := extracted
+	if single && prefix != "" {
+		if info, err := os.Stat(filepath.Join(extracted, prefix)); err == nil && info.IsDir() {
+			src = filepath.Join(extracted, prefix)
+		}
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("%s is empty", loc)
+	}
+	for _, e := range entries {
+		if err := os.Rename(filepath.Join(src, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", loc, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package boot
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/lua"
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+)
+
+// Path is the repo URI of the boot file that holds all module accessors.
+const Path = "//boot/boot.lua"
+
+// lines splits Lua source into lines, keeping a trailing newline as a
+// final empty line so that joining is lossless.
+func lines(src []byte) []string {
+	return strings.Split(string(src), "\n")
+}
+
+func join(l []string) []byte {
+	return []byte(strings.Join(l, "\n"))
+}
+
+// findFunction returns the line indexes of the header and the closing,
+// unindented "end" of the top level function declared by header.
+func findFunction(l []string, header *regexp.Regexp) (int, int, bool) {
+	for i, line := range l {
+		if !header.MatchString(line) {
+			continue
+		}
+		for j := i + 1; j < len(l); j++ {
+			if strings.TrimRight(l[j], " \t\r") == "end" {
+				return i, j, true
+			}
+		}
+		return i, -1, false
+	}
+	return -1, -1, false
+}
+
+// RemoveAccessor removes the Moonlight:<accessor>() function, along with the
+// annotation comments directly above it, from the boot file source.
+func RemoveAccessor(src []byte, accessor string) ([]byte, bool) {
+	l := lines(src)
+	header := regexp.MustCompile(`^function\s+Moonlight:` + regexp.QuoteMeta(accessor) + `\s*\(\s*\)`)
+	start, end, ok := findFunction(l, header)
+	if !ok {
+		return src, false
+	}
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(l[start-1]), "---") {
+		start--
+	}
+
+	// Collapse the blank lines around the removed block so that deleting
+	// an accessor never leaves a double gap behind.
+	before := start > 0 && strings.TrimSpace(l[start-1]) == ""
+	after := end+1 < len(l) && strings.TrimSpace(l[end+1]) == ""
+	if before && (after || end+1 == len(l)) {
+		start--
+	}
+
+	out := append([]string{}, l[:start]...)
+	out = append(out, l[end+1:]...)
+	return join(out), true
+}
+
+// localAccessor matches "local x = self:<accessor>()" inside Moonlight:Start.
+func localAccessor(accessor string) *regexp.Regexp {
+	return regexp.MustCompile(`^\s*local\s+([A-Za-z_][A-Za-z0-9_]*)\s*=\s*self:` + regexp.QuoteMeta(accessor) + `\s*\(\s*\)\s*$`)
+}
+
+var startHeader = regexp.MustCompile(`^function\s+Moonlight:Start\s*\(\s*\)`)
+
+// StartUsages returns the lines of Moonlight:Start that use the local
+// variable bound to <accessor> for anything other than its Boot() call.
+func StartUsages(src []byte, accessor string) []string {
+	l := lines(src)
+	start, end, ok := findFunction(l, startHeader)
+	if !ok {
+		return nil
+	}
+	reLocal := localAccessor(accessor)
+	var name string
+	for i := start + 1; i < end; i++ {
+		if match := reLocal.FindStringSubmatch(l[i]); match != nil {
+			name = match[1]
+			break
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	reUse := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+	reBoot := bootCall(name)
+	var usages []string
+	for i := start + 1; i < end; i++ {
+		if reLocal.MatchString(l[i]) || reBoot.MatchString(l[i]) {
+			continue
+		}
+		if reUse.MatchString(l[i]) {
+			usages = append(usages, fmt.Sprintf("%s:%d: %s", strings.TrimPrefix(Path, "//"), i+1, strings.TrimSpace(l[i])))
+		}
+	}
+	return usages
+}
+
+func bootCall(name string) *regexp.Regexp {
+	return regexp.MustCompile(`^\s*` + regexp.QuoteMeta(name) + `:Boot\s*\(\s*\)\s*;?\s*$`)
+}
+
+// RemoveBoot removes the local accessor variable for <accessor> and its
+// Boot() call from Moonlight:Start.
+func RemoveBoot(src []byte, accessor string) ([]byte, bool) {
+	l := lines(src)
+	start, end, ok := findFunction(l, startHeader)
+	if !ok {
+		return src, false
+	}
+	reLocal := localAccessor(accessor)
+	var name string
+	for i := start + 1; i < end; i++ {
+		if match := reLocal.FindStringSubmatch(l[i]); match != nil {
+			name = match[1]
+			break
+		}
+	}
+	if name == "" {
+		return src, false
+	}
+
+	reBoot := bootCall(name)
+	out := append([]string{}, l[:start+1]...)
+	for i := start + 1; i < end; i++ {
+		if reLocal.MatchString(l[i]) || reBoot.MatchString(l[i]) {
+			continue
+		}
+		out = append(out, l[i])
+	}
+	out = append(out, l[end:]...)
+	return join(out), true
+}
+
+// RenameAccessor renames the Moonlight:<from>() accessor to <to>, points it at
+// the class toClass instead of fromClass, and updates every self:<from>() call
+// in the boot file.
+func RenameAccessor(src []byte, from, to, fromClass, toClass string) ([]byte, bool) {
+	l := lines(src)
+	header := regexp.MustCompile(`^function\s+Moonlight:` + regexp.QuoteMeta(from) + `\s*\(\s*\)`)
+	start, end, ok := findFunction(l, header)
+	if !ok {
+		return src, false
+	}
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(l[start-1]), "---") {
+		start--
+	}
+
+	reClass := regexp.MustCompile(`\b` + regexp.QuoteMeta(fromClass) + `\b`)
+	reAccessor := regexp.MustCompile(`\bMoonlight:` + regexp.QuoteMeta(from) + `\b`)
+	for i := start; i <= end; i++ {
+		l[i] = reAccessor.ReplaceAllString(l[i], "Moonlight:"+to)
+		if strings.HasPrefix(strings.TrimSpace(l[i]), "---@return") || strings.Contains(l[i], "self.classes.") {
+			l[i] = reClass.ReplaceAllString(l[i], toClass)
+		}
+	}
+
+	reSelf := regexp.MustCompile(`\bself:` + regexp.QuoteMeta(from) + `(\s*\()`)
+	out := reSelf.ReplaceAllString(strings.Join(l, "\n"), "self:"+to+"$1")
+	return []byte(out), true
+}
+
+// Accessor is a Moonlight:GetX() function declared in the boot file.
+type Accessor = scan.Accessor
+
+// Accessors returns every Moonlight:GetX() accessor in the boot file source,
+// in source order.
+func Accessors(src []byte) []Accessor {
+	return scan.Accessors(src)
+}
+
+// startBody is the parsed body of Moonlight:Start: the local accessor
+// variables at the top of the function, and the Boot() calls that follow.
+type startBody struct {
+	// header is the line index of the function declaration.
+	header int
+	// locals maps each accessor to the last line of its local variable.
+	locals map[string]int
+	// vars maps each local variable name to its accessor.
+	vars map[string]string
+	// boots maps each local variable name to the last line of its Boot()
+	// call.
+	boots map[string]int
+	// lastLocal and lastBoot are the line indexes of the last local
+	// accessor and the last Boot() call, or -1.
+	lastLocal, lastBoot int
+	indent              string
+}
+
+// parseStart parses the boot file source and reads the statements of the
+// Moonlight:Start function.
+func parseStart(src []byte) (*startBody, error) {
+	chunk, err := lua.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", Path, err)
+	}
+	var start *lua.FunctionStmt
+	for _, stmt := range chunk.Block.Stmts {
+		if f, ok := stmt.(*lua.FunctionStmt); ok && f.Name.String() == "Moonlight:Start" {
+			start = f
+			break
+		}
+	}
+	if start == nil {
+		return nil, fmt.Errorf("could not find function Moonlight:Start() in %s", Path)
+	}
+
+	l := lines(src)
+	body := &startBody{
+		header:    start.Pos().Line - 1,
+		locals:    make(map[string]int),
+		vars:      make(map[string]string),
+		boots:     make(map[string]int),
+		lastLocal: -1,
+		lastBoot:  -1,
+		indent:    "  ",
+	}
+	for _, stmt := range start.Func.Body.Stmts {
+		last := stmt.End().Line - 1
+		switch stmt := stmt.(type) {
+		case *lua.LocalAssign:
+			if len(stmt.Names) != 1 || len(stmt.Values) != 1 {
+				continue
+			}
+			call, ok := stmt.Values[0].(*lua.MethodCall)
+			if !ok || !isName(call.Recv, "self") || !strings.HasPrefix(call.Method.Name, "Get") || len(call.Args) != 0 {
+				continue
+			}
+			body.locals[call.Method.Name] = last
+			body.vars[stmt.Names[0].Name] = call.Method.Name
+			body.lastLocal = last
+			pos := stmt.Pos()
+			if indent := l[pos.Line-1][:pos.Column-1]; strings.TrimSpace(indent) == "" {
+				body.indent = indent
+			}
+		case *lua.CallStmt:
+			call, ok := stmt.Call.(*lua.MethodCall)
+			if !ok || call.Method.Name != "Boot" || len(call.Args) != 0 {
+				continue
+			}
+			if recv, ok := call.Recv.(*lua.Ident); ok {
+				body.boots[recv.Name] = last
+				body.lastBoot = last
+			}
+		}
+	}
+	return body, nil
+}
+
+// isName reports if e is the name name.
+func isName(e lua.Expr, name string) bool {
+	ident, ok := e.(*lua.Ident)
+	return ok && ident.Name == name
+}
+
+// AddBoot binds <accessor> to a local variable named name in Moonlight:Start
+// and calls its Boot() function. The new lines are placed right after the
+// local variable and Boot() call of the module bound to after, or at the end
+// of each group when after is empty.
+func AddBoot(src []byte, accessor, name, after string) ([]byte, error) {
+	body, err := parseStart(src)
+	if err != nil {
+		return nil, err
+	}
+	l := lines(src)
+	if _, ok := body.locals[accessor]; ok {
+		return nil, fmt.Errorf("Moonlight:Start already binds self:%s()", accessor)
+	}
+	if _, ok := body.vars[name]; ok {
+		return nil, fmt.Errorf("Moonlight:Start already has a local named %q", name)
+	}
+
+	localAt, bootAt := body.lastLocal, body.lastBoot
+	if after != "" {
+		var ok bool
+		if localAt, ok = body.locals[after]; !ok {
+			return nil, fmt.Errorf("Moonlight:Start does not bind self:%s()", after)
+		}
+		var afterVar string
+		for _, v := range slices.Sorted(maps.Keys(body.vars)) {
+			if body.vars[v] == after {
+				afterVar = v
+				break
+			}
+		}
+		if bootAt, ok = body.boots[afterVar]; !ok {
+			return nil, fmt.Errorf("Moonlight:Start does not call %s:Boot()", afterVar)
+		}
+	}
+
+	localLine := fmt.Sprintf("%slocal %s = self:%s()", body.indent, name, accessor)
+	bootLine := fmt.Sprintf("%s%s:Boot()", body.indent, name)
+
+	// Without any existing boot calls, start a new group after the locals.
+	if bootAt == -1 {
+		if localAt == -1 {
+			localAt = body.header
+		}
+		out := append([]string{}, l[:localAt+1]...)
+		out = append(out, localLine, "", bootLine)
+		out = append(out, l[localAt+1:]...)
+		return join(out), nil
+	}
+	if localAt == -1 {
+		localAt = body.header
+	}
+
+	// Insert the later line first so the earlier index stays valid.
+	out := append([]string{}, l...)
+	if bootAt > localAt {
+		out = insertLine(out, bootAt+1, bootLine)
+		out = insertLine(out, localAt+1, localLine)
+	} else {
+		out = insertLine(out, localAt+1, localLine)
+		out = insertLine(out, bootAt+1, bootLine)
+	}
+	return join(out), nil
+}
+
+// ResolveAccessor finds the accessor bound in Moonlight:Start for module,
+// which may be a local variable name (e.g. darkTheme), an accessor name
+// (e.g. GetSave) or a class name (e.g. save). Exact matches win over
+// matches that ignore case, and those are tried in accessor order so that
+// the result does not depend on map iteration.
+func ResolveAccessor(src []byte, module string) (string, error) {
+	body, err := parseStart(src)
+	if err != nil {
+		return "", err
+	}
+	if accessor, ok := body.vars[module]; ok {
+		return accessor, nil
+	}
+	for _, accessor := range []string{module, "Get" + module} {
+		if _, ok := body.locals[accessor]; ok {
+			return accessor, nil
+		}
+	}
+	accessors := slices.Sorted(maps.Keys(body.locals))
+	for _, accessor := range accessors {
+		if strings.EqualFold(accessor, module) || strings.EqualFold(accessor, "Get"+module) {
+			return accessor, nil
+		}
+	}
+	return "", fmt.Errorf("module %q is not booted in Moonlight:Start", module)
+}
+
+func insertLine(l []string, at int, line string) []string {
+	l = append(l, "")
+	copy(l[at+1:], l[at:])
+	l[at] = line
+	return l
+}
@@ -0,0 +1,187 @@
+package boot
+
+import "testing"
+
+const startSrc = `local addon = ...
+
+-- function Moonlight:Start() in a comment
+function Moonlight:Start()
+  local save = self:GetSave()
+  local darkTheme = self:GetDarkTheme()
+  local darktheme = self:GetDarktheme()
+  local loader = self:GetLoader(
+  )
+  if self.debug then
+    local debug = self:GetDebug()
+    debug:Boot()
+  end
+
+  save:Boot()
+  loader:Boot()
+  darkTheme:Boot()
+end
+`
+
+func TestAddBoot(t *testing.T) {
+	tests := []struct {
+		name, accessor, local, after string
+		want                         string
+	}{
+		{
+			name:     "last",
+			accessor: "GetBag",
+			local:    "bag",
+			want: `local addon = ...
+
+-- function Moonlight:Start() in a comment
+function Moonlight:Start()
+  local save = self:GetSave()
+  local darkTheme = self:GetDarkTheme()
+  local darktheme = self:GetDarktheme()
+  local loader = self:GetLoader(
+  )
+  local bag = self:GetBag()
+  if self.debug then
+    local debug = self:GetDebug()
+    debug:Boot()
+  end
+
+  save:Boot()
+  loader:Boot()
+  darkTheme:Boot()
+  bag:Boot()
+end
+`,
+		},
+		{
+			name:     "after",
+			accessor: "GetBag",
+			local:    "bag",
+			after:    "GetSave",
+			want: `local addon = ...
+
+-- function Moonlight:Start() in a comment
+function Moonlight:Start()
+  local save = self:GetSave()
+  local bag = self:GetBag()
+  local darkTheme = self:GetDarkTheme()
+  local darktheme = self:GetDarktheme()
+  local loader = self:GetLoader(
+  )
+  if self.debug then
+    local debug = self:GetDebug()
+    debug:Boot()
+  end
+
+  save:Boot()
+  bag:Boot()
+  loader:Boot()
+  darkTheme:Boot()
+end
+`,
+		},
+		{
+			name:     "after a multi line local",
+			accessor: "GetBag",
+			local:    "bag",
+			after:    "GetLoader",
+			want: `local addon = ...
+
+-- function Moonlight:Start() in a comment
+function Moonlight:Start()
+  local save = self:GetSave()
+  local darkTheme = self:GetDarkTheme()
+  local darktheme = self:GetDarktheme()
+  local loader = self:GetLoader(
+  )
+  local bag = self:GetBag()
+  if self.debug then
+    local debug = self:GetDebug()
+    debug:Boot()
+  end
+
+  save:Boot()
+  loader:Boot()
+  bag:Boot()
+  darkTheme:Boot()
+end
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddBoot([]byte(startSrc), tt.accessor, tt.local, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("AddBoot() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddBootErrors(t *testing.T) {
+	tests := []struct {
+		name, src, accessor, local, after string
+	}{
+		{"already bound", startSrc, "GetSave", "save2", ""},
+		{"local taken", startSrc, "GetBag", "save", ""},
+		{"unknown after", startSrc, "GetBag", "bag", "GetBank"},
+		{"after without a Boot call", startSrc, "GetBag", "bag", "GetDarktheme"},
+		// Locals in nested blocks are not part of the boot sequence.
+		{"nested after", startSrc, "GetBag", "bag", "GetDebug"},
+		{"no Start", "function Moonlight:Stop()\nend\n", "GetBag", "bag", ""},
+		{"does not parse", "function Moonlight:Start()\n", "GetBag", "bag", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := AddBoot([]byte(tt.src), tt.accessor, tt.local, tt.after); err == nil {
+				t.Error("AddBoot() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestAddBootWithoutBootCalls(t *testing.T) {
+	src := "function Moonlight:Start()\n    local save = self:GetSave()\nend\n"
+	got, err := AddBoot([]byte(src), "GetBag", "bag", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "function Moonlight:Start()\n    local save = self:GetSave()\n    local bag = self:GetBag()\n\n    bag:Boot()\nend\n"
+	if string(got) != want {
+		t.Errorf("AddBoot() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestResolveAccessor(t *testing.T) {
+	tests := []struct {
+		module, want string
+	}{
+		{"save", "GetSave"},
+		{"GetSave", "GetSave"},
+		{"darkTheme", "GetDarkTheme"},
+		{"darktheme", "GetDarktheme"},
+		{"DarkTheme", "GetDarkTheme"},
+		{"Darktheme", "GetDarktheme"},
+		// Both accessors match without case, and the first in order wins.
+		{"DARKTHEME", "GetDarkTheme"},
+		{"getloader", "GetLoader"},
+	}
+	for _, tt := range tests {
+		for range 10 {
+			got, err := ResolveAccessor([]byte(startSrc), tt.module)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveAccessor(%q) = %q, want %q", tt.module, got, tt.want)
+				break
+			}
+		}
+	}
+	if _, err := ResolveAccessor([]byte(startSrc), "debug"); err == nil {
+		t.Error(`ResolveAccessor("debug") succeeded, want an error for a local in a nested block`)
+	}
+}
@@ -0,0 +1,118 @@
+package boot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+// NewBootCmd creates and returns the boot command with its subcommands.
+func NewBootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "boot",
+		Short: "Manage boot/boot.lua",
+	}
+
+	cmd.AddCommand(newSyncCmd())
+
+	return cmd
+}
+
+func newSyncCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Regenerate the Moonlight:GetX() accessors in boot/boot.lua",
+		Long: `Scans every file listed in Moonlight.toc for moonlight:NewClass registrations and regenerates
+a sorted, clearly delimited block in boot/boot.lua with one annotated accessor per class.
+Hand written code outside the block is preserved. Accessors whose class is no longer
+registered anywhere are removed and reported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+
+			classes, err := tocClasses(root)
+			if err != nil {
+				return err
+			}
+
+			bootPath, err := util.GetRepoPath(Path)
+			if err != nil {
+				return fmt.Errorf("failed to get boot path: %w", err)
+			}
+			src, err := os.ReadFile(bootPath)
+			if err != nil {
+				return fmt.Errorf("failed to read boot file: %w", err)
+			}
+
+			result := Sync(src, classes)
+
+			cs := changeset.New(root)
+			cs.Write(bootPath, result.Src, 0)
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to write boot file: %w", err)
+			}
+			if dryRun {
+				return nil
+			}
+
+			fmt.Printf("Synced %d accessors in %s\n", len(classes), Path)
+			for _, name := range result.Added {
+				fmt.Printf("  added Moonlight:%s()\n", name)
+			}
+			for _, a := range result.Removed {
+				fmt.Printf("  removed Moonlight:%s(), class %q is no longer registered\n", a.Name, a.Class)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+	return cmd
+}
+
+// tocClasses returns every class registered by the Lua files the TOC loads,
+// in load order.
+func tocClasses(root string) ([]string, error) {
+	tocFile, err := toc.Load(filepath.Join(root, "Moonlight.toc"))
+	if err != nil {
+		return nil, err
+	}
+	files, err := tocFile.LoadedFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var classes []string
+	seen := make(map[string]bool)
+	for _, rel := range files {
+		if !strings.HasSuffix(rel, ".lua") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		for _, class := range scan.Classes(content) {
+			if !seen[class] {
+				seen[class] = true
+				classes = append(classes, class)
+			}
+		}
+	}
+	return classes, nil
+}
@@ -0,0 +1,192 @@
+package boot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	blockBegin = "-- BEGIN GENERATED ACCESSORS"
+	blockEnd   = "-- END GENERATED ACCESSORS"
+)
+
+// blockHeader is written right after blockBegin.
+var blockHeader = []string{
+	"-- This block is generated by 'moonlight boot sync' from every moonlight:NewClass",
+	"-- call in the files listed in Moonlight.toc. Do not edit it by hand.",
+}
+
+// AccessorName returns the name of the accessor for class, e.g. GetSave for save.
+func AccessorName(class string) string {
+	if class == "" {
+		return "Get"
+	}
+	r := []rune(class)
+	return "Get" + string(unicode.ToUpper(r[0])) + string(r[1:])
+}
+
+// accessorLines renders a single accessor function.
+func accessorLines(name, class string) []string {
+	return []string{
+		"---@return " + class,
+		"function Moonlight:" + name + "()",
+		"  return self.classes." + class,
+		"end",
+	}
+}
+
+// findBlock returns the line indexes of the generated block markers.
+func findBlock(l []string) (int, int, bool) {
+	begin, end := -1, -1
+	for i, line := range l {
+		switch strings.TrimSpace(line) {
+		case blockBegin:
+			begin = i
+		case blockEnd:
+			if begin != -1 {
+				end = i
+			}
+		}
+		if end != -1 {
+			return begin, end, true
+		}
+	}
+	return -1, -1, false
+}
+
+// SyncResult describes what Sync changed.
+type SyncResult struct {
+	Src []byte
+	// Added lists the accessors that did not exist before.
+	Added []string
+	// Removed lists the accessors dropped because their class is no longer
+	// registered.
+	Removed []Accessor
+}
+
+// Sync regenerates the accessor block in the boot file source so that it
+// holds exactly one sorted accessor for every class in classes. Every
+// accessor that returns a class from self.classes, inside or outside the
+// block, is folded into the block; all other code is left untouched.
+// Existing accessor names are kept, so callers never break.
+func Sync(src []byte, classes []string) SyncResult {
+	existing := Accessors(src)
+	nameFor := make(map[string]string)
+	for _, a := range existing {
+		if a.Class != "" && nameFor[a.Class] == "" {
+			nameFor[a.Class] = a.Name
+		}
+	}
+
+	wanted := make(map[string]bool)
+	var result SyncResult
+	for _, class := range classes {
+		wanted[class] = true
+		if nameFor[class] == "" {
+			nameFor[class] = AccessorName(class)
+			result.Added = append(result.Added, nameFor[class])
+		}
+	}
+	for _, a := range existing {
+		if a.Class != "" && !wanted[a.Class] {
+			result.Removed = append(result.Removed, a)
+		}
+	}
+
+	// Strip the old block and every class accessor outside of it. A
+	// placeholder line marks where the block was so it is regenerated in place.
+	const placeholder = "\x00moonlight-accessor-block\x00"
+	l := lines(src)
+	if begin, end, ok := findBlock(l); ok {
+		l = append(l[:begin:begin], append([]string{placeholder}, l[end+1:]...)...)
+	}
+	for _, a := range existing {
+		if a.Class == "" {
+			continue
+		}
+		for {
+			stripped, ok := RemoveAccessor(join(l), a.Name)
+			if !ok {
+				break
+			}
+			l = lines(stripped)
+		}
+	}
+	at := slices.Index(l, placeholder)
+	if at != -1 {
+		l = slices.Delete(l, at, at+1)
+	}
+
+	classes = slices.Clone(classes)
+	sort.Slice(classes, func(i, j int) bool { return nameFor[classes[i]] < nameFor[classes[j]] })
+	block := []string{blockBegin}
+	block = append(block, blockHeader...)
+	for _, class := range classes {
+		block = append(block, "")
+		block = append(block, accessorLines(nameFor[class], class)...)
+	}
+	block = append(block, blockEnd)
+
+	if at == -1 {
+		// Without an existing block, append it at the end of the file.
+		for len(l) > 0 && strings.TrimSpace(l[len(l)-1]) == "" {
+			l = l[:len(l)-1]
+		}
+		l = append(l, "")
+		l = append(l, block...)
+		l = append(l, "")
+	} else {
+		l = append(l[:at:at], append(block, l[at:]...)...)
+	}
+
+	result.Src = join(l)
+	return result
+}
+
+// InsertAccessor adds an accessor for class to the boot file source. When
+// the generated block exists the accessor is placed in sorted order inside
+// it, otherwise it is appended to the end of the file.
+func InsertAccessor(src []byte, class string) ([]byte, error) {
+	name := AccessorName(class)
+	for _, a := range Accessors(src) {
+		if a.Name == name {
+			return nil, fmt.Errorf("accessor Moonlight:%s() already exists in %s", name, Path)
+		}
+	}
+
+	l := lines(src)
+	begin, end, ok := findBlock(l)
+	if !ok {
+		// Add a newline before appending the new content
+		return []byte(string(src) + "\n" + strings.Join(accessorLines(name, class), "\n")), nil
+	}
+
+	// Insert before the first accessor in the block that sorts after the
+	// new one, keeping the blank line separators between them.
+	at := end
+	for _, a := range Accessors(src) {
+		i := a.Line - 1
+		if i <= begin || i >= end || a.Name < name {
+			continue
+		}
+		at = i
+		for at > begin+1 && strings.HasPrefix(strings.TrimSpace(l[at-1]), "---") {
+			at--
+		}
+		break
+	}
+
+	entry := accessorLines(name, class)
+	if at == end {
+		entry = append([]string{""}, entry...)
+	} else {
+		entry = append(entry, "")
+	}
+	out := append([]string{}, l[:at]...)
+	out = append(out, entry...)
+	out = append(out, l[at:]...)
+	return join(out), nil
+}
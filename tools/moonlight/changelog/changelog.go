@@ -0,0 +1,208 @@
+// Package changelog builds release notes from the git history.
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{"markdown", "bbcode"}
+
+// generalGroup holds commits that touch files in more than one top level
+// directory, or only files at the repo root.
+const generalGroup = "General"
+
+// conventionalTypes maps conventional commit types to their group title, in
+// the order the groups are listed.
+var conventionalTypes = []struct{ Type, Title string }{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"revert", "Reverts"},
+	{"docs", "Documentation"},
+	{"style", "Style"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"chore", "Chores"},
+}
+
+var reConventional = regexp.MustCompile(`^([a-z]+)(?:\(([^)]+)\))?(!)?:\s*(.+)$`)
+
+// Entry is a single change.
+type Entry struct {
+	Hash    string
+	Subject string
+	// Scope is the conventional commit scope, if any.
+	Scope    string
+	Breaking bool
+}
+
+// Group is a titled list of changes, newest first.
+type Group struct {
+	Title   string
+	Entries []Entry
+}
+
+// Changelog lists the changes in a release.
+type Changelog struct {
+	Name    string
+	Version string
+	Date    time.Time
+	// Since is the tag the changes were made after, or empty.
+	Since  string
+	Groups []Group
+}
+
+// New groups the commits of a range into a changelog for version. Commits
+// with a conventional prefix, e.g. "fix(bag): ...", are grouped by type.
+// Other commits are grouped by the top level directory they change, e.g.
+// bag, sonata or themes.
+func New(name, version string, date time.Time, r vcs.Range) *Changelog {
+	c := &Changelog{Name: name, Version: version, Date: date, Since: r.Since}
+	groups := make(map[string]*Group)
+	for _, commit := range r.Commits {
+		entry, title := classify(commit)
+		g, ok := groups[title]
+		if !ok {
+			g = &Group{Title: title}
+			groups[title] = g
+		}
+		g.Entries = append(g.Entries, entry)
+	}
+
+	for _, t := range conventionalTypes {
+		if g, ok := groups[t.Title]; ok {
+			c.Groups = append(c.Groups, *g)
+			delete(groups, t.Title)
+		}
+	}
+	var dirs []string
+	for title := range groups {
+		if title != generalGroup {
+			dirs = append(dirs, title)
+		}
+	}
+	slices.Sort(dirs)
+	for _, title := range dirs {
+		c.Groups = append(c.Groups, *groups[title])
+	}
+	if g, ok := groups[generalGroup]; ok {
+		c.Groups = append(c.Groups, *g)
+	}
+	return c
+}
+
+// classify returns the entry for a commit, and the title of its group.
+func classify(commit vcs.Commit) (Entry, string) {
+	entry := Entry{Hash: commit.ShortHash(), Subject: commit.Subject}
+	if match := reConventional.FindStringSubmatch(commit.Subject); match != nil {
+		for _, t := range conventionalTypes {
+			if t.Type == match[1] {
+				entry.Scope = match[2]
+				entry.Breaking = match[3] == "!" || strings.Contains(commit.Body, "BREAKING CHANGE")
+				entry.Subject = match[4]
+				return entry, t.Title
+			}
+		}
+	}
+
+	var dirs []string
+	for _, file := range commit.Files {
+		dir, _, nested := strings.Cut(file, "/")
+		if !nested {
+			return entry, generalGroup
+		}
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) != 1 {
+		return entry, generalGroup
+	}
+	return entry, dirs[0]
+}
+
+// Empty reports if the changelog has no changes.
+func (c *Changelog) Empty() bool {
+	return len(c.Groups) == 0
+}
+
+// Render renders the changelog in one of Formats.
+func (c *Changelog) Render(format string) ([]byte, error) {
+	switch format {
+	case "markdown":
+		return c.Markdown(), nil
+	case "bbcode":
+		return c.BBCode(), nil
+	default:
+		return nil, fmt.Errorf("unknown changelog format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// sinceLine describes where the changes start.
+func (c *Changelog) sinceLine() string {
+	switch {
+	case c.Since == "" && c.Empty():
+		return "No changes."
+	case c.Since == "":
+		return "All changes."
+	case c.Empty():
+		return fmt.Sprintf("No changes since %s.", c.Since)
+	default:
+		return fmt.Sprintf("Changes since %s.", c.Since)
+	}
+}
+
+// Markdown renders the changelog as markdown.
+func (c *Changelog) Markdown() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", c.Name)
+	fmt.Fprintf(&b, "## %s (%s)\n\n", c.Version, c.Date.UTC().Format("2006-01-02"))
+	fmt.Fprintf(&b, "%s\n", c.sinceLine())
+	for _, g := range c.Groups {
+		fmt.Fprintf(&b, "\n### %s\n\n", g.Title)
+		for _, e := range g.Entries {
+			b.WriteString("- ")
+			if e.Breaking {
+				b.WriteString("**BREAKING:** ")
+			}
+			if e.Scope != "" {
+				fmt.Fprintf(&b, "**%s:** ", e.Scope)
+			}
+			fmt.Fprintf(&b, "%s (%s)\n", e.Subject, e.Hash)
+		}
+	}
+	return []byte(b.String())
+}
+
+// BBCode renders the changelog as BBCode, for addon sites that do not
+// support markdown.
+func (c *Changelog) BBCode() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[size=5][b]%s[/b][/size]\n\n", c.Name)
+	fmt.Fprintf(&b, "[size=4][b]%s[/b] (%s)[/size]\n\n", c.Version, c.Date.UTC().Format("2006-01-02"))
+	fmt.Fprintf(&b, "%s\n", c.sinceLine())
+	for _, g := range c.Groups {
+		fmt.Fprintf(&b, "\n[b]%s[/b]\n[list]\n", g.Title)
+		for _, e := range g.Entries {
+			b.WriteString("[*]")
+			if e.Breaking {
+				b.WriteString("[b]BREAKING:[/b] ")
+			}
+			if e.Scope != "" {
+				fmt.Fprintf(&b, "[b]%s:[/b] ", e.Scope)
+			}
+			fmt.Fprintf(&b, "%s (%s)\n", e.Subject, e.Hash)
+		}
+		b.WriteString("[/list]\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,99 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+)
+
+func TestNew(t *testing.T) {
+	r := vcs.Range{
+		Since: "v1.0.0",
+		Commits: []vcs.Commit{
+			{Hash: "1111111aaa", Subject: "fix(bag): Keep the sort order", Files: []string{"bag/bag.lua"}},
+			{Hash: "2222222bbb", Subject: "Tweak the sonata engine", Files: []string{"sonata/engine.lua", "sonata/render.lua"}},
+			{Hash: "3333333ccc", Subject: "feat!: Drop the old theme API", Files: []string{"themes/theme.lua"}},
+			{Hash: "4444444ddd", Subject: "Update the TOC", Files: []string{"Moonlight.toc"}},
+			{Hash: "5555555eee", Subject: "feat(themes): Add a dark theme", Body: "BREAKING CHANGE: themes are renamed", Files: []string{"themes/dark.lua"}},
+			{Hash: "6666666fff", Subject: "Move the pool", Files: []string{"pool/pool.lua", "bag/bag.lua"}},
+			{Hash: "7777777000", Subject: "Fix the bag layout", Files: []string{"bag/layout.lua"}},
+			{Hash: "8888888111", Subject: "wip: not a conventional type", Files: []string{"bag/bag.lua"}},
+			{Hash: "9999999222", Subject: "Empty commit"},
+		},
+	}
+	c := New("Moonlight", "v1.1.0", time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), r)
+
+	type entry struct {
+		subject, scope string
+		breaking       bool
+	}
+	want := []struct {
+		title   string
+		entries []entry
+	}{
+		{"Features", []entry{{"Drop the old theme API", "", true}, {"Add a dark theme", "themes", true}}},
+		{"Bug Fixes", []entry{{"Keep the sort order", "bag", false}}},
+		{"bag", []entry{{"Fix the bag layout", "", false}, {"wip: not a conventional type", "", false}}},
+		{"sonata", []entry{{"Tweak the sonata engine", "", false}}},
+		{generalGroup, []entry{{"Update the TOC", "", false}, {"Move the pool", "", false}, {"Empty commit", "", false}}},
+	}
+	if len(c.Groups) != len(want) {
+		var titles []string
+		for _, g := range c.Groups {
+			titles = append(titles, g.Title)
+		}
+		t.Fatalf("groups = %q, want %d groups", titles, len(want))
+	}
+	for i, w := range want {
+		g := c.Groups[i]
+		if g.Title != w.title {
+			t.Errorf("group %d = %q, want %q", i, g.Title, w.title)
+			continue
+		}
+		if len(g.Entries) != len(w.entries) {
+			t.Errorf("group %q has %d entries, want %d", g.Title, len(g.Entries), len(w.entries))
+			continue
+		}
+		for j, we := range w.entries {
+			e := g.Entries[j]
+			if e.Subject != we.subject || e.Scope != we.scope || e.Breaking != we.breaking {
+				t.Errorf("group %q entry %d = %+v, want %+v", g.Title, j, e, we)
+			}
+		}
+	}
+	if got := c.Groups[1].Entries[0].Hash; got != "1111111" {
+		t.Errorf("hash = %q, want the short hash", got)
+	}
+
+	md := string(c.Markdown())
+	for _, line := range []string{
+		"## v1.1.0 (2026-10-18)",
+		"Changes since v1.0.0.",
+		"- **BREAKING:** **themes:** Add a dark theme (5555555)",
+		"- **bag:** Keep the sort order (1111111)",
+	} {
+		if !strings.Contains(md, line+"\n") {
+			t.Errorf("Markdown() does not contain %q:\n%s", line, md)
+		}
+	}
+}
+
+func TestNewEmpty(t *testing.T) {
+	tests := []struct {
+		since, want string
+	}{
+		{"", "No changes."},
+		{"v1.0.0", "No changes since v1.0.0."},
+	}
+	for _, tt := range tests {
+		c := New("Moonlight", "v1.0.0", time.Time{}, vcs.Range{Since: tt.since})
+		if !c.Empty() {
+			t.Errorf("Empty() = false for a range without commits")
+		}
+		if got := c.sinceLine(); got != tt.want {
+			t.Errorf("sinceLine() = %q, want %q", got, tt.want)
+		}
+	}
+}
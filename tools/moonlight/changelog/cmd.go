@@ -0,0 +1,109 @@
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+	"github.com/spf13/cobra"
+)
+
+// Name is the addon name used as the changelog title.
+const Name = "Moonlight"
+
+// defaultOutput returns the file the changelog is written to in format
+// when no output is given, so that BBCode never ends up in a markdown file.
+func defaultOutput(format string) string {
+	if format == "markdown" {
+		return "//CHANGELOG.md"
+	}
+	return "//CHANGELOG.txt"
+}
+
+// Generate builds the changelog for HEAD of the repo at root, listing the
+// commits after since, or after the previous tag when since is empty.
+// version overrides the version of HEAD when set.
+func Generate(root, since, version string) (*Changelog, error) {
+	rev, err := vcs.Head(root)
+	if err != nil {
+		return nil, err
+	}
+	r, err := vcs.Log(root, since)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		version = rev.Version()
+	}
+	return New(Name, version, rev.Time, r), nil
+}
+
+// NewChangelogCmd creates and returns the changelog command.
+func NewChangelogCmd() *cobra.Command {
+	var (
+		since  string
+		format string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "changelog",
+		Short: "Generate CHANGELOG.md from the git history",
+		Long: `Walks the commits between the previous tag and HEAD and writes them to CHANGELOG.md.
+Commits with a conventional prefix, such as "feat:" or "fix(bag):", are grouped by type.
+Other commits are grouped by the top level directory they change, e.g. bag, sonata or
+themes, and commits that change several directories are listed under General.
+
+Use --since to start after another tag, --format bbcode for addon sites that do not support
+markdown, which writes CHANGELOG.txt instead, and --output - to print the changelog instead
+of writing it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(Formats, format) {
+				return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+			}
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			c, err := Generate(root, since, "")
+			if err != nil {
+				return err
+			}
+			content, err := c.Render(format)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				output = defaultOutput(format)
+			}
+			if output == "-" {
+				_, err := os.Stdout.Write(content)
+				return err
+			}
+			path, err := util.GetRepoPath(output)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				return fmt.Errorf("failed to write changelog: %w", err)
+			}
+			entries := 0
+			for _, g := range c.Groups {
+				entries += len(g.Entries)
+			}
+			fmt.Printf("Wrote %d changes to %s\n", entries, output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Tag to start after, defaults to the previous tag")
+	cmd.Flags().StringVarP(&format, "format", "f", "markdown", "Output format, markdown or bbcode")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write, or - for stdout, defaults to //CHANGELOG.md or //CHANGELOG.txt for bbcode")
+
+	return cmd
+}
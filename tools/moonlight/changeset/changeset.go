@@ -0,0 +1,361 @@
+package changeset
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// op is the kind of a single staged change.
+type op int
+
+const (
+	opWrite op = iota
+	opDelete
+)
+
+// change is a single staged file mutation.
+type change struct {
+	op   op
+	path string
+	// content is the new file content for writes staged with Write.
+	content []byte
+	// source is the file to copy the new content from for writes staged
+	// with Copy, so that large trees are never held in memory.
+	source string
+	// mode is the permission of the written file. Zero keeps the mode of
+	// an existing file, or uses 0644 for a new one.
+	mode os.FileMode
+}
+
+// Set is an ordered set of staged file writes and deletes. Nothing touches
+// the disk until Apply, which applies every change or none of them.
+type Set struct {
+	root    string
+	changes []*change
+	byPath  map[string]*change
+}
+
+// New returns an empty change set. Paths are shown relative to root in
+// diffs and summaries.
+func New(root string) *Set {
+	return &Set{
+		root:   root,
+		byPath: make(map[string]*change),
+	}
+}
+
+func (s *Set) stage(c *change) {
+	c.path = filepath.Clean(c.path)
+	if prev, ok := s.byPath[c.path]; ok {
+		*prev = *c
+		return
+	}
+	s.changes = append(s.changes, c)
+	s.byPath[c.path] = c
+}
+
+// Write stages path to be written with content.
+func (s *Set) Write(path string, content []byte, mode os.FileMode) {
+	s.stage(&change{op: opWrite, path: path, content: content, mode: mode})
+}
+
+// Copy stages path to be written with the content of source at apply time.
+func (s *Set) Copy(source, path string, mode os.FileMode) {
+	s.stage(&change{op: opWrite, path: path, source: source, mode: mode})
+}
+
+// Delete stages path to be removed.
+func (s *Set) Delete(path string) {
+	s.stage(&change{op: opDelete, path: path})
+}
+
+// Read returns the content of path as it will be after the staged changes
+// are applied, falling back to the file on disk.
+func (s *Set) Read(path string) ([]byte, error) {
+	if c, ok := s.byPath[filepath.Clean(path)]; ok {
+		return c.newContent()
+	}
+	return os.ReadFile(path)
+}
+
+// Empty reports if nothing has been staged.
+func (s *Set) Empty() bool {
+	return len(s.changes) == 0
+}
+
+func (c *change) newContent() ([]byte, error) {
+	switch {
+	case c.op == opDelete:
+		return nil, os.ErrNotExist
+	case c.source != "":
+		return os.ReadFile(c.source)
+	default:
+		return c.content, nil
+	}
+}
+
+// rel returns path relative to the set root for display.
+func (s *Set) rel(path string) string {
+	if rel, err := filepath.Rel(s.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// Summary returns a sorted, human readable line for every staged change.
+func (s *Set) Summary() []string {
+	var lines []string
+	for _, c := range s.changes {
+		_, err := os.Stat(c.path)
+		exists := err == nil
+		switch {
+		case c.op == opDelete:
+			lines = append(lines, "delete "+s.rel(c.path))
+		case exists:
+			lines = append(lines, "update "+s.rel(c.path))
+		default:
+			lines = append(lines, "create "+s.rel(c.path))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Commit applies the staged changes, or when dryRun is set, writes a
+// unified diff of them to w instead.
+func (s *Set) Commit(dryRun bool, w io.Writer) error {
+	if dryRun {
+		return s.Diff(w)
+	}
+	return s.Apply()
+}
+
+// applied records what Apply did to a single path so it can be undone.
+type applied struct {
+	path string
+	// backup is where the original file was moved to, if it existed.
+	backup string
+	// written is true when a new file was renamed into place.
+	written bool
+}
+
+// Apply applies every staged change. New content is first written to a
+// temp file next to its destination and then renamed into place, and the
+// original file is kept as a backup until every change has succeeded. On
+// any error all changes made so far are rolled back.
+func (s *Set) Apply() (err error) {
+	var createdDirs []string
+	temps := make(map[*change]string)
+	var done []applied
+
+	defer func() {
+		for _, tmp := range temps {
+			os.Remove(tmp)
+		}
+		if err == nil {
+			return
+		}
+		if rerr := rollback(done, createdDirs); rerr != nil {
+			err = fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+	}()
+
+	// Prepare every write before touching any destination, so that most
+	// failures (bad source files, full disks) happen before anything changes.
+	for _, c := range s.changes {
+		if c.op != opWrite {
+			continue
+		}
+		if info, err := os.Stat(c.path); err == nil && info.IsDir() {
+			return fmt.Errorf("failed to stage %s: it is a directory", s.rel(c.path))
+		}
+		dirs, err := mkdirAll(filepath.Dir(c.path))
+		createdDirs = append(createdDirs, dirs...)
+		if err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", s.rel(c.path), err)
+		}
+		tmp, err := writeTemp(c)
+		if err != nil {
+			return fmt.Errorf("failed to stage %s: %w", s.rel(c.path), err)
+		}
+		temps[c] = tmp
+	}
+
+	for _, c := range s.changes {
+		a := applied{path: c.path}
+		if _, err := os.Lstat(c.path); err == nil {
+			a.backup = backupPath(c.path)
+			if err := os.Rename(c.path, a.backup); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", s.rel(c.path), err)
+			}
+		}
+		if c.op == opWrite {
+			if err := os.Rename(temps[c], c.path); err != nil {
+				done = append(done, a)
+				return fmt.Errorf("failed to write %s: %w", s.rel(c.path), err)
+			}
+			delete(temps, c)
+			a.written = true
+		}
+		done = append(done, a)
+	}
+
+	// Every change succeeded, the backups are no longer needed.
+	for _, a := range done {
+		if a.backup != "" {
+			os.Remove(a.backup)
+		}
+	}
+	s.pruneDirs()
+	return nil
+}
+
+// rollback undoes applied changes in reverse order and removes any
+// directories created for them.
+func rollback(done []applied, createdDirs []string) error {
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		a := done[i]
+		if a.written {
+			if err := os.Remove(a.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+		if a.backup != "" {
+			if err := os.Rename(a.backup, a.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for i := len(createdDirs) - 1; i >= 0; i-- {
+		os.Remove(createdDirs[i])
+	}
+	return errors.Join(errs...)
+}
+
+// pruneDirs removes directories left empty by staged deletes.
+func (s *Set) pruneDirs() {
+	for _, c := range s.changes {
+		if c.op != opDelete {
+			continue
+		}
+		for dir := filepath.Dir(c.path); dir != s.root && strings.HasPrefix(dir, s.root); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+}
+
+// mkdirAll creates dir and any missing parents, returning the directories
+// it created, outermost first.
+func mkdirAll(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	var created []string
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], 0755); err != nil && !os.IsExist(err) {
+			return created, err
+		}
+		created = append(created, missing[i])
+	}
+	return created, nil
+}
+
+// writeTemp writes the new content of c to a temp file in the same
+// directory as its destination, so that the final rename is atomic.
+func writeTemp(c *change) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(c.path), "."+filepath.Base(c.path)+".moonlight-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+
+	if c.source != "" {
+		var src *os.File
+		if src, err = os.Open(c.source); err == nil {
+			_, err = io.Copy(f, src)
+			src.Close()
+		}
+	} else {
+		_, err = f.Write(c.content)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		mode := c.mode
+		if mode == 0 {
+			mode = 0644
+			if info, err := os.Stat(c.path); err == nil {
+				mode = info.Mode().Perm()
+			}
+		}
+		err = os.Chmod(tmp, mode)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+func backupPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".moonlight-backup")
+}
+
+// SyncDir stages dst to become an exact copy of the directory tree at src:
+// every file under src is copied, and every file under dst that does not
+// exist in src is deleted.
+func (s *Set) SyncDir(src, dst string) error {
+	wanted := make(map[string]bool)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		wanted[target] = true
+		s.Copy(path, target, info.Mode().Perm())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stage %s: %w", src, err)
+	}
+
+	if _, err := os.Stat(dst); os.IsNotExist(err) {
+		return nil
+	}
+	err = filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !wanted[path] {
+			s.Delete(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stage %s: %w", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package changeset
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tree returns the content of every file under root by slash separated
+// relative path, and the relative paths of its directories.
+func tree(t *testing.T, root string) (map[string]string, []string) {
+	t.Helper()
+	files := make(map[string]string)
+	var dirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, filepath.ToSlash(rel))
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files, dirs
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.lua":       "old a",
+		"gone/b.lua":  "old b",
+		"source.lua":  "copied",
+		"keep/c.lua":  "old c",
+		"keep/d.lua":  "old d",
+		"rewrite.lua": "old",
+	})
+	s := New(root)
+	s.Write(filepath.Join(root, "a.lua"), []byte("new a"), 0)
+	s.Write(filepath.Join(root, "new/deep/e.lua"), []byte("new e"), 0)
+	s.Copy(filepath.Join(root, "source.lua"), filepath.Join(root, "f.lua"), 0)
+	s.Delete(filepath.Join(root, "gone/b.lua"))
+	s.Delete(filepath.Join(root, "keep/c.lua"))
+	// A later change to the same path replaces the earlier one.
+	s.Write(filepath.Join(root, "rewrite.lua"), []byte("first"), 0)
+	s.Delete(filepath.Join(root, "rewrite.lua"))
+	if err := s.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, dirs := tree(t, root)
+	want := map[string]string{
+		"a.lua":          "new a",
+		"new/deep/e.lua": "new e",
+		"source.lua":     "copied",
+		"f.lua":          "copied",
+		"keep/d.lua":     "old d",
+	}
+	if !maps.Equal(files, want) {
+		t.Errorf("files after Apply() = %q, want %q", files, want)
+	}
+	// The directory left empty by the delete is removed.
+	for _, dir := range dirs {
+		if dir == "gone" {
+			t.Error("Apply() left the empty gone directory behind")
+		}
+	}
+}
+
+func TestApplyRollback(t *testing.T) {
+	original := map[string]string{
+		"a.lua":     "old a",
+		"b.lua":     "old b",
+		"dir/c.lua": "old c",
+	}
+	tests := []struct {
+		name string
+		// setup stages the changes, and prepares the failure.
+		setup func(t *testing.T, root string, s *Set)
+	}{
+		{
+			name: "missing copy source",
+			setup: func(t *testing.T, root string, s *Set) {
+				s.Write(filepath.Join(root, "a.lua"), []byte("new a"), 0)
+				s.Write(filepath.Join(root, "new/d.lua"), []byte("new d"), 0)
+				s.Copy(filepath.Join(root, "missing.lua"), filepath.Join(root, "b.lua"), 0)
+			},
+		},
+		{
+			name: "write over a directory",
+			setup: func(t *testing.T, root string, s *Set) {
+				s.Write(filepath.Join(root, "a.lua"), []byte("new a"), 0)
+				s.Write(filepath.Join(root, "dir"), []byte("not a directory"), 0)
+			},
+		},
+		{
+			// The backup of b.lua can not be made, so the rename phase fails
+			// after a.lua has been replaced and dir/c.lua deleted.
+			name: "backup fails partway",
+			setup: func(t *testing.T, root string, s *Set) {
+				if err := os.MkdirAll(backupPath(filepath.Join(root, "b.lua")), 0755); err != nil {
+					t.Fatal(err)
+				}
+				s.Write(filepath.Join(root, "a.lua"), []byte("new a"), 0)
+				s.Write(filepath.Join(root, "new/deep/d.lua"), []byte("new d"), 0)
+				s.Delete(filepath.Join(root, "dir/c.lua"))
+				s.Write(filepath.Join(root, "b.lua"), []byte("new b"), 0)
+				s.Write(filepath.Join(root, "e.lua"), []byte("new e"), 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, original)
+			s := New(root)
+			tt.setup(t, root, s)
+			_, beforeDirs := tree(t, root)
+
+			if err := s.Apply(); err == nil {
+				t.Fatal("Apply() succeeded, want an error")
+			}
+			files, dirs := tree(t, root)
+			if !maps.Equal(files, original) {
+				t.Errorf("files after a failed Apply() = %q, want the originals %q", files, original)
+			}
+			// Only the directories that existed before remain, so no
+			// created directory, temp file or backup is left behind.
+			if len(dirs) != len(beforeDirs) {
+				t.Errorf("directories after a failed Apply() = %q, want %q", dirs, beforeDirs)
+			}
+		})
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"src/a.lua":      "new a",
+		"src/sub/b.lua":  "b",
+		"dst/a.lua":      "old a",
+		"dst/stale.lua":  "stale",
+		"dst/old/c.lua":  "c",
+		"dst/sub/b.lua":  "b",
+		"other/keep.lua": "keep",
+	})
+	s := New(root)
+	if err := s.SyncDir(filepath.Join(root, "src"), filepath.Join(root, "dst")); err != nil {
+		t.Fatal(err)
+	}
+	// A destination that does not exist yet is created.
+	if err := s.SyncDir(filepath.Join(root, "src"), filepath.Join(root, "fresh")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, dirs := tree(t, root)
+	want := map[string]string{
+		"src/a.lua":       "new a",
+		"src/sub/b.lua":   "b",
+		"dst/a.lua":       "new a",
+		"dst/sub/b.lua":   "b",
+		"fresh/a.lua":     "new a",
+		"fresh/sub/b.lua": "b",
+		"other/keep.lua":  "keep",
+	}
+	if !maps.Equal(files, want) {
+		t.Errorf("files after SyncDir() = %q, want %q", files, want)
+	}
+	for _, dir := range dirs {
+		if dir == "dst/old" {
+			t.Error("SyncDir() left the emptied dst/old directory behind")
+		}
+	}
+}
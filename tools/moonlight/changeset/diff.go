@@ -0,0 +1,272 @@
+package changeset
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each hunk.
+const contextLines = 3
+
+// Diff writes a unified diff of every staged change to w.
+func (s *Set) Diff(w io.Writer) error {
+	for _, c := range s.changes {
+		before, err := os.ReadFile(c.path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", s.rel(c.path), err)
+		}
+		existed := err == nil
+
+		after, err := c.newContent()
+		if err != nil && c.op != opDelete {
+			return fmt.Errorf("failed to read staged content for %s: %w", s.rel(c.path), err)
+		}
+
+		from, to := "a/"+s.rel(c.path), "b/"+s.rel(c.path)
+		if !existed {
+			from = "/dev/null"
+		}
+		if c.op == opDelete {
+			if !existed {
+				continue
+			}
+			to = "/dev/null"
+		}
+		if existed && c.op == opWrite && bytes.Equal(before, after) {
+			continue
+		}
+		if isBinary(before) || isBinary(after) {
+			fmt.Fprintf(w, "Binary files %s and %s differ\n", from, to)
+			continue
+		}
+
+		fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+		writeHunks(w, splitLines(before), splitLines(after))
+	}
+	return nil
+}
+
+func isBinary(b []byte) bool {
+	return bytes.IndexByte(b, 0) != -1
+}
+
+// splitLines splits content into lines. A missing newline at the end of
+// the file is kept as a marker on the last line, as diff does.
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	text := string(b)
+	noEOL := !strings.HasSuffix(text, "\n")
+	l := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if noEOL {
+		l[len(l)-1] += "\n\\ No newline at end of file"
+	}
+	return l
+}
+
+// edit is a single line of a diff script.
+type edit struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// diffLines computes the shortest edit script from a to b. The common
+// prefix and suffix are matched directly, and only the middle is diffed.
+func diffLines(a, b []string) []edit {
+	var prefix, suffix []edit
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, edit{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]edit{{' ', a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	var middle []edit
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			middle = append(middle, edit{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			middle = append(middle, edit{'-', line})
+		}
+	default:
+		middle = myers(a, b)
+	}
+	return append(append(prefix, middle...), suffix...)
+}
+
+// myers computes the shortest edit script from a to b using the linear
+// space variant of the Myers algorithm: it finds the middle snake of the
+// shortest path, and recurses on the parts before and after it. Memory
+// stays linear in the length of the input, however far apart a and b are.
+func myers(a, b []string) []edit {
+	var edits []edit
+	var walk func(a, b []string)
+	walk = func(a, b []string) {
+		var suffix []string
+		for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+			edits = append(edits, edit{' ', a[0]})
+			a, b = a[1:], b[1:]
+		}
+		for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+			suffix = append(suffix, a[len(a)-1])
+			a, b = a[:len(a)-1], b[:len(b)-1]
+		}
+
+		switch {
+		case len(a) == 0:
+			for _, line := range b {
+				edits = append(edits, edit{'+', line})
+			}
+		case len(b) == 0:
+			for _, line := range a {
+				edits = append(edits, edit{'-', line})
+			}
+		default:
+			// Without a common prefix or suffix, a and b are at least two
+			// edits apart, so both halves are smaller than the whole.
+			x, y, u, v := middleSnake(a, b)
+			walk(a[:x], b[:y])
+			for _, line := range a[x:u] {
+				edits = append(edits, edit{' ', line})
+			}
+			walk(a[u:], b[v:])
+		}
+
+		for i := len(suffix) - 1; i >= 0; i-- {
+			edits = append(edits, edit{' ', suffix[i]})
+		}
+	}
+	walk(a, b)
+	return edits
+}
+
+// middleSnake returns the snake, from (x, y) to (u, v), in the middle of a
+// shortest edit path from a to b. It runs the Myers search forward from
+// the start and backward from the end at once, until the two meet.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	offset := n + m + 1
+	// forward holds the furthest x on each diagonal k = x - y of the
+	// forward search, and backward the furthest distance from the end on
+	// each diagonal of the backward search.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d <= (n+m+1)/2; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			if kr := delta - k; odd && kr >= -(d-1) && kr <= d-1 && u+backward[offset+kr] >= n {
+				return x, y, u, v
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var xr int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				xr = backward[offset+k+1]
+			} else {
+				xr = backward[offset+k-1] + 1
+			}
+			yr := xr - k
+			ur, vr := xr, yr
+			for ur < n && vr < m && a[n-1-ur] == b[m-1-vr] {
+				ur++
+				vr++
+			}
+			backward[offset+k] = ur
+			if kf := delta - k; !odd && kf >= -d && kf <= d && ur+forward[offset+kf] >= n {
+				return n - ur, m - vr, n - xr, m - yr
+			}
+		}
+	}
+	// The searches always meet by the middle of the longest possible path.
+	panic("changeset: no middle snake")
+}
+
+// writeHunks writes the unified diff hunks that turn a into b.
+func writeHunks(w io.Writer, a, b []string) {
+	edits := diffLines(a, b)
+
+	for i := 0; i < len(edits); {
+		// Find the next change.
+		for i < len(edits) && edits[i].kind == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			return
+		}
+
+		start := max(i-contextLines, 0)
+		end := i
+		// Extend the hunk while the gap between changes is small enough
+		// to share context.
+		for end < len(edits) {
+			if edits[end].kind != ' ' {
+				end++
+				continue
+			}
+			gap := end
+			for gap < len(edits) && edits[gap].kind == ' ' {
+				gap++
+			}
+			if gap == len(edits) || gap-end > 2*contextLines {
+				end = min(end+contextLines, len(edits))
+				break
+			}
+			end = gap
+		}
+
+		// Line numbers of the hunk start in a and b.
+		aLine, bLine := 1, 1
+		for _, e := range edits[:start] {
+			if e.kind != '+' {
+				aLine++
+			}
+			if e.kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, e := range edits[start:end] {
+			if e.kind != '+' {
+				aCount++
+			}
+			if e.kind != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(w, "%c%s\n", e.kind, e.text)
+		}
+		i = end
+	}
+}
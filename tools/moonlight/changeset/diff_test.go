@@ -0,0 +1,86 @@
+package changeset
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLines(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		l := make([]string, rng.Intn(30))
+		for i := range l {
+			l[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return l
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := lines(), lines()
+		edits := diffLines(a, b)
+
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range edits {
+			if e.kind != '+' {
+				gotA = append(gotA, e.text)
+			}
+			if e.kind != '-' {
+				gotB = append(gotB, e.text)
+			}
+			if e.kind != ' ' {
+				changes++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("diffLines(%q, %q) does not rebuild its input: %v", a, b, edits)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); changes != want {
+			t.Fatalf("diffLines(%q, %q) = %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+func TestWriteHunks(t *testing.T) {
+	a := strings.Split("a b c d e f g h i j k l m", " ")
+	b := strings.Split("a b c X e f g h i j k l m n", " ")
+	var out strings.Builder
+	writeHunks(&out, a, b)
+	want := `@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++X
+ e
+ f
+ g
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if out.String() != want {
+		t.Errorf("writeHunks() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
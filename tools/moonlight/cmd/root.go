@@ -5,7 +5,13 @@ import (
 	"os"
 
 	"github.com/Cidan/Moonlight/tools/moonlight/anno"
+	"github.com/Cidan/Moonlight/tools/moonlight/boot"
+	"github.com/Cidan/Moonlight/tools/moonlight/changelog"
+	"github.com/Cidan/Moonlight/tools/moonlight/install"
 	"github.com/Cidan/Moonlight/tools/moonlight/module"
+	"github.com/Cidan/Moonlight/tools/moonlight/pack"
+	"github.com/Cidan/Moonlight/tools/moonlight/release"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +26,9 @@ This tool provides various commands to help with common tasks.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		// Errors go to stderr, so that they never end up in output that is
+		// meant for other tools, such as module list --json.
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -28,4 +36,11 @@ func Execute() {
 func init() {
 	rootCmd.AddCommand(module.NewModuleCmd())
 	rootCmd.AddCommand(anno.NewAnnoCmd())
+	rootCmd.AddCommand(boot.NewBootCmd())
+	rootCmd.AddCommand(toc.NewTocCmd())
+	rootCmd.AddCommand(pack.NewPackageCmd())
+	rootCmd.AddCommand(changelog.NewChangelogCmd())
+	rootCmd.AddCommand(release.NewReleaseCmd())
+	rootCmd.AddCommand(install.NewInstallCmd())
+	rootCmd.AddCommand(install.NewWatchCmd())
 }
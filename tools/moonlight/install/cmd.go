@@ -0,0 +1,157 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/pack"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+// NewInstallCmd creates and returns the install command.
+func NewInstallCmd() *cobra.Command {
+	var (
+		addonsDir string
+		link      bool
+		force     bool
+		dryRun    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the addon into the game's AddOns directory",
+		Long: `Copies exactly the files a packaged build contains into <addons-dir>/Moonlight,
+after applying packager markers and substituting tokens. Files that are no longer part of
+the package are removed from the installed copy, and unchanged files are not touched.
+
+The AddOns directory is remembered in the user config file, so --addons-dir is only needed
+the first time. With --link, the addon folder is a symlink to the repo instead, and
+--force replaces an existing installed copy with the link.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			dir, err := AddonsDir(addonsDir, !dryRun)
+			if err != nil {
+				return err
+			}
+			meta, err := pack.LoadMeta(root)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dir, meta.PackageAs)
+
+			if link {
+				if dryRun {
+					fmt.Printf("Would link %s to %s\n", target, root)
+					return nil
+				}
+				if err := Link(root, target, force); err != nil {
+					return err
+				}
+				fmt.Printf("Linked %s to %s\n", target, root)
+				return nil
+			}
+
+			if IsLink(target) {
+				if dryRun {
+					fmt.Printf("Would replace the link at %s with a copy\n", target)
+					return nil
+				}
+				if err := os.Remove(target); err != nil {
+					return fmt.Errorf("failed to remove link %s: %w", target, err)
+				}
+			}
+
+			b, err := pack.NewBuild(root)
+			if err != nil {
+				return err
+			}
+			cs := changeset.New(dir)
+			if err := Stage(cs, dir, b.Files); err != nil {
+				return err
+			}
+			if cs.Empty() {
+				fmt.Printf("%s is up to date\n", target)
+				return nil
+			}
+			summary := cs.Summary()
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to install: %w", err)
+			}
+			if dryRun {
+				return nil
+			}
+			for _, line := range summary {
+				fmt.Println(line)
+			}
+			fmt.Printf("Installed %d files into %s\n", len(b.Files), dir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addonsDir, "addons-dir", "", "Path to the game's Interface/AddOns directory, remembered for later runs")
+	cmd.Flags().BoolVar(&link, "link", false, "Symlink the addon folder to the repo instead of copying files")
+	cmd.Flags().BoolVar(&force, "force", false, "With --link, replace an existing installed copy")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+
+	return cmd
+}
+
+// NewWatchCmd creates and returns the watch command.
+func NewWatchCmd() *cobra.Command {
+	var (
+		addonsDir string
+		interval  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Mirror changes into the installed addon as files are saved",
+		Long: `Watches the repo and mirrors every change into the installed copy of the addon, so that
+a /reload in game picks it up. Only files whose packaged content changed are copied, and
+files that are no longer part of the package are removed. Runs until interrupted.
+
+The AddOns directory is the one remembered by install, or --addons-dir.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			dir, err := AddonsDir(addonsDir, false)
+			if err != nil {
+				return err
+			}
+			meta, err := pack.LoadMeta(root)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dir, meta.PackageAs)
+			if IsLink(target) {
+				fmt.Printf("%s links to the repo, changes are already live\n", target)
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			fmt.Printf("Watching %s, mirroring into %s\n", root, dir)
+			return Watch(ctx, root, dir, interval, func(line string) {
+				fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), line)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&addonsDir, "addons-dir", "", "Path to the game's Interface/AddOns directory")
+	cmd.Flags().DurationVar(&interval, "interval", 500*time.Millisecond, "How often to check for changes")
+
+	return cmd
+}
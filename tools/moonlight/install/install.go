@@ -0,0 +1,121 @@
+// Package install copies or links the addon into the AddOns directory of
+// a game client.
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/pack"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+)
+
+// AddonsDir resolves the AddOns directory: the flag value when set, which
+// is then remembered in the user config, or the remembered one otherwise.
+func AddonsDir(flag string, remember bool) (string, error) {
+	cfg, err := util.LoadUserConfig()
+	if err != nil {
+		return "", err
+	}
+	if flag == "" {
+		if cfg.AddonsDir == "" {
+			return "", fmt.Errorf("no AddOns directory is configured, pass --addons-dir <path>")
+		}
+		flag = cfg.AddonsDir
+	}
+	dir, err := filepath.Abs(flag)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", flag, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("AddOns directory %s does not exist", dir)
+	}
+	if remember && cfg.AddonsDir != dir {
+		cfg.AddonsDir = dir
+		if err := cfg.Save(); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// Stage stages the changes that make the addon folders under addonsDir
+// hold exactly files: files whose content differs are written, and files
+// in the addon folders that are not part of the package are deleted.
+// Unchanged files are not touched, so repeated calls only copy what changed.
+func Stage(cs *changeset.Set, addonsDir string, files []pack.File) error {
+	wanted := make(map[string]bool, len(files))
+	folders := make(map[string]bool)
+	for _, f := range files {
+		target := filepath.Join(addonsDir, filepath.FromSlash(f.Dest))
+		wanted[target] = true
+		folder, _, _ := strings.Cut(f.Dest, "/")
+		folders[folder] = true
+
+		current, err := os.ReadFile(target)
+		if err == nil && bytes.Equal(current, f.Content) {
+			continue
+		}
+		cs.Write(target, f.Content, 0644)
+	}
+
+	for folder := range folders {
+		dir := filepath.Join(addonsDir, folder)
+		if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && !wanted[path] {
+				cs.Delete(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// Link points the addon folder under addonsDir at the repo root, so that
+// the client loads the working tree directly. An existing link is
+// replaced; an existing directory is only replaced when force is set.
+func Link(root, target string, force bool) error {
+	info, err := os.Lstat(target)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", target, err)
+	case info.Mode()&os.ModeSymlink != 0:
+		if dest, err := os.Readlink(target); err == nil && dest == root {
+			return nil
+		}
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("failed to remove link %s: %w", target, err)
+		}
+	case info.IsDir() && force:
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+	default:
+		return fmt.Errorf("%s already exists, pass --force to replace it with a link", target)
+	}
+	if err := os.Symlink(root, target); err != nil {
+		return fmt.Errorf("failed to link %s: %w", target, err)
+	}
+	return nil
+}
+
+// IsLink reports if path is a symlink.
+func IsLink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,135 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// writeFiles writes files, keyed by slash separated path, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newRepo creates a git repository holding files, with a single commit.
+func newRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, files)
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000, 0)}
+	if _, err := wt.Commit("initial", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestMirror(t *testing.T) {
+	root := newRepo(t, map[string]string{
+		".pkgmeta":          "package-as: Moonlight\nignore:\n  - annotations\n",
+		"Moonlight.toc":     "## Interface: 110200\ncore.lua\nbag/bag.lua\n",
+		"core.lua":          "local core = {}\n",
+		"bag/bag.lua":       "local bag = {}\n",
+		"annotations/x.lua": "---@meta\n",
+	})
+	addons := t.TempDir()
+	writeFiles(t, addons, map[string]string{"Other/other.lua": "-- another addon\n"})
+	log := func(string) {}
+
+	if err := mirror(root, addons, log); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"Moonlight/Moonlight.toc", "Moonlight/core.lua", "Moonlight/bag/bag.lua", "Moonlight/CHANGELOG.md"} {
+		if !exists(filepath.Join(addons, rel)) {
+			t.Errorf("%s was not installed", rel)
+		}
+	}
+	if exists(filepath.Join(addons, "Moonlight", "annotations")) {
+		t.Error("ignored annotations were installed")
+	}
+
+	// Drop bag from the package, and change core.
+	writeFiles(t, root, map[string]string{
+		"Moonlight.toc": "## Interface: 110200\ncore.lua\n",
+		"core.lua":      "local core = {version = 2}\n",
+	})
+	if err := os.RemoveAll(filepath.Join(root, "bag")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mirror(root, addons, log); err != nil {
+		t.Fatal(err)
+	}
+	if exists(filepath.Join(addons, "Moonlight", "bag", "bag.lua")) {
+		t.Error("bag/bag.lua was not deleted from the installed copy")
+	}
+	content, err := os.ReadFile(filepath.Join(addons, "Moonlight", "core.lua"))
+	if err != nil || string(content) != "local core = {version = 2}\n" {
+		t.Errorf("core.lua = %q, %v, want the changed content", content, err)
+	}
+	if !exists(filepath.Join(addons, "Other", "other.lua")) {
+		t.Error("another addon was deleted")
+	}
+
+	// Nothing changed, so nothing is written.
+	var lines []string
+	if err := mirror(root, addons, func(line string) { lines = append(lines, line) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 0 {
+		t.Errorf("mirror of an unchanged repo logged %q, want nothing", lines)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".pkgmeta":             "ignore:\n  - annotations\n",
+		".gitignore":           "*.log\n",
+		".git/HEAD":            "ref: refs/heads/main\n",
+		"Moonlight.toc":        "core.lua\n",
+		"core.lua":             "local core = {}\n",
+		"debug.log":            "log\n",
+		"annotations/meta.lua": "---@meta\n",
+	})
+	stamps, err := snapshot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".pkgmeta", "Moonlight.toc", "core.lua"}
+	if len(stamps) != len(want) {
+		t.Errorf("snapshot stamped %d files, want %d: %v", len(stamps), len(want), stamps)
+	}
+	for _, rel := range want {
+		if _, ok := stamps[filepath.Join(root, rel)]; !ok {
+			t.Errorf("snapshot did not stamp %s", rel)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/pack"
+)
+
+// stamp is the size and modification time of a file, used to notice
+// changes without reading every file.
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot stamps every file under root that can end up in the package,
+// and .pkgmeta. Paths that are ignored, such as annotations/ and tools/,
+// are not walked at all, so that a tick stays cheap.
+func snapshot(root string) (map[string]stamp, error) {
+	meta, err := pack.LoadMeta(root)
+	if err != nil {
+		// The broken .pkgmeta is reported when the package is built. Until
+		// it is fixed, the files are still watched with the defaults.
+		meta = pack.DefaultMeta()
+	}
+	stamps := make(map[string]stamp)
+	add := func(p string) error {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		stamps[p] = stamp{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	}
+	if err := pack.Walk(root, meta, func(p, rel string) error { return add(p) }); err != nil {
+		return nil, err
+	}
+	// .pkgmeta is a dot file, which is never packaged, but it changes the
+	// package.
+	if err := add(filepath.Join(root, pack.MetaFile)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// Watch mirrors the package of the repo at root into addonsDir every time a
+// file changes, until ctx is done. Only files whose packaged content
+// changed are written. Errors, such as an unclosed packager marker in a
+// file that is being edited, are reported and the watch goes on.
+func Watch(ctx context.Context, root, addonsDir string, interval time.Duration, log func(string)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last map[string]stamp
+	for {
+		stamps, err := snapshot(root)
+		if err != nil {
+			log(fmt.Sprintf("error: %v", err))
+		} else if !maps.Equal(stamps, last) {
+			last = stamps
+			if err := mirror(root, addonsDir, log); err != nil {
+				log(fmt.Sprintf("error: %v", err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// mirror builds the package and applies the difference to addonsDir.
+func mirror(root, addonsDir string, log func(string)) error {
+	b, err := pack.NewBuild(root)
+	if err != nil {
+		return err
+	}
+	cs := changeset.New(addonsDir)
+	if err := Stage(cs, addonsDir, b.Files); err != nil {
+		return err
+	}
+	if cs.Empty() {
+		return nil
+	}
+	summary := cs.Summary()
+	if err := cs.Apply(); err != nil {
+		return fmt.Errorf("failed to update %s: %w", addonsDir, err)
+	}
+	for _, line := range summary {
+		log(line)
+	}
+	return nil
+}
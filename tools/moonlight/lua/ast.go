@@ -0,0 +1,322 @@
+package lua
+
+// Node is a node of the AST.
+type Node interface {
+	// Pos is the position of the first byte of the node, and End the
+	// position right after its last byte.
+	Pos() Pos
+	End() Pos
+}
+
+type span struct {
+	Start Pos
+	Stop  Pos
+}
+
+func (s span) Pos() Pos { return s.Start }
+func (s span) End() Pos { return s.Stop }
+
+// Stmt is a statement.
+type Stmt interface {
+	Node
+	// Doc returns the comments right above the statement, with no blank
+	// line or code between them and the statement.
+	Doc() []*Comment
+	init(start, end Pos, doc []*Comment)
+}
+
+type stmt struct {
+	span
+	Comments []*Comment
+}
+
+func (s *stmt) Doc() []*Comment { return s.Comments }
+
+func (s *stmt) init(start, end Pos, doc []*Comment) {
+	s.Start, s.Stop, s.Comments = start, end, doc
+}
+
+// Expr is an expression.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Chunk is a parsed source file.
+type Chunk struct {
+	Block *Block
+	// Comments holds every comment of the file, in source order.
+	Comments []*Comment
+}
+
+// Block is a list of statements.
+type Block struct {
+	span
+	Stmts []Stmt
+}
+
+// Statements.
+type (
+	// LocalAssign is local a, b = x, y. Values is empty without =.
+	LocalAssign struct {
+		stmt
+		Names  []*Ident
+		Values []Expr
+	}
+
+	// Assign is a, b.c, d[e] = x, y, z.
+	Assign struct {
+		stmt
+		Targets []Expr
+		Values  []Expr
+	}
+
+	// CallStmt is a function or method call used as a statement.
+	CallStmt struct {
+		stmt
+		Call Expr
+	}
+
+	// Do is do ... end.
+	Do struct {
+		stmt
+		Body *Block
+	}
+
+	// While is while Cond do ... end.
+	While struct {
+		stmt
+		Cond Expr
+		Body *Block
+	}
+
+	// Repeat is repeat ... until Cond.
+	Repeat struct {
+		stmt
+		Body *Block
+		Cond Expr
+	}
+
+	// If is if ... then ... elseif ... else ... end. Clauses holds the if
+	// and every elseif; Else is nil without an else.
+	If struct {
+		stmt
+		Clauses []*IfClause
+		Else    *Block
+	}
+
+	// NumericFor is for Var = Start, Limit, Step do ... end. Step is nil
+	// when it is left out.
+	NumericFor struct {
+		stmt
+		Var   *Ident
+		Start Expr
+		Limit Expr
+		Step  Expr
+		Body  *Block
+	}
+
+	// GenericFor is for a, b in Exprs do ... end.
+	GenericFor struct {
+		stmt
+		Names []*Ident
+		Exprs []Expr
+		Body  *Block
+	}
+
+	// FunctionStmt is function a.b:c() ... end.
+	FunctionStmt struct {
+		stmt
+		Name *FuncName
+		Func *Function
+	}
+
+	// LocalFunction is local function a() ... end.
+	LocalFunction struct {
+		stmt
+		Name *Ident
+		Func *Function
+	}
+
+	// Return is return a, b.
+	Return struct {
+		stmt
+		Values []Expr
+	}
+
+	// Break is break.
+	Break struct {
+		stmt
+	}
+)
+
+// IfClause is a condition and the block it guards.
+type IfClause struct {
+	Cond Expr
+	Body *Block
+}
+
+// FuncName is the name of a function statement: a path of fields, and
+// the method name for a function defined with a colon.
+type FuncName struct {
+	span
+	Path   []*Ident
+	Method *Ident
+}
+
+// String returns the name as written, e.g. a.b:c.
+func (n *FuncName) String() string {
+	s := ""
+	for i, part := range n.Path {
+		if i > 0 {
+			s += "."
+		}
+		s += part.Name
+	}
+	if n.Method != nil {
+		s += ":" + n.Method.Name
+	}
+	return s
+}
+
+// Expressions.
+type (
+	// Ident is a name.
+	Ident struct {
+		span
+		Name string
+	}
+
+	// Nil is nil.
+	Nil struct {
+		span
+	}
+
+	// Bool is true or false.
+	Bool struct {
+		span
+		Value bool
+	}
+
+	// NumberLit is a numeric literal, as written.
+	NumberLit struct {
+		span
+		Raw string
+	}
+
+	// StringLit is a string literal, with its escapes decoded.
+	StringLit struct {
+		span
+		Value string
+	}
+
+	// Vararg is ....
+	Vararg struct {
+		span
+	}
+
+	// Function is a function body, of a function expression or statement.
+	// Params does not hold the implicit self of a method.
+	Function struct {
+		span
+		Params []*Ident
+		Vararg bool
+		Body   *Block
+	}
+
+	// Table is a table constructor.
+	Table struct {
+		span
+		Fields []*TableField
+	}
+
+	// Binary is X Op Y.
+	Binary struct {
+		span
+		Op string
+		X  Expr
+		Y  Expr
+	}
+
+	// Unary is Op X.
+	Unary struct {
+		span
+		Op string
+		X  Expr
+	}
+
+	// Field is X.Name.
+	Field struct {
+		span
+		X    Expr
+		Name *Ident
+	}
+
+	// Index is X[Key].
+	Index struct {
+		span
+		X   Expr
+		Key Expr
+	}
+
+	// Call is Func(Args).
+	Call struct {
+		span
+		Func Expr
+		Args []Expr
+	}
+
+	// MethodCall is Recv:Method(Args).
+	MethodCall struct {
+		span
+		Recv   Expr
+		Method *Ident
+		Args   []Expr
+	}
+
+	// Paren is (X), which truncates X to a single value.
+	Paren struct {
+		span
+		X Expr
+	}
+)
+
+// TableField is a field of a table constructor. Key is an *Ident for
+// name = value, any expression for [key] = value, and nil for a
+// positional value.
+type TableField struct {
+	Key   Expr
+	Value Expr
+}
+
+func (*Ident) exprNode()      {}
+func (*Nil) exprNode()        {}
+func (*Bool) exprNode()       {}
+func (*NumberLit) exprNode()  {}
+func (*StringLit) exprNode()  {}
+func (*Vararg) exprNode()     {}
+func (*Function) exprNode()   {}
+func (*Table) exprNode()      {}
+func (*Binary) exprNode()     {}
+func (*Unary) exprNode()      {}
+func (*Field) exprNode()      {}
+func (*Index) exprNode()      {}
+func (*Call) exprNode()       {}
+func (*MethodCall) exprNode() {}
+func (*Paren) exprNode()      {}
+
+// DottedName returns the name that e spells, such as a or a.b.c, and if
+// e is a name or a chain of fields on one.
+func DottedName(e Expr) (string, bool) {
+	switch e := e.(type) {
+	case *Ident:
+		return e.Name, true
+	case *Field:
+		base, ok := DottedName(e.X)
+		if !ok {
+			return "", false
+		}
+		return base + "." + e.Name.Name, true
+	}
+	return "", false
+}
@@ -0,0 +1,376 @@
+// Package lua reads Lua 5.1 source, the dialect of the WoW client. It
+// splits the source into tokens, or parses it into an AST that keeps the
+// comments, so that tools can rewrite the source and find declarations and
+// the annotations above them precisely.
+package lua
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in the source.
+type Pos struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line and Column start at 1. Columns count bytes.
+	Line   int
+	Column int
+}
+
+// Error is a syntax error at a position in the source.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+// TokenKind is the kind of a token.
+type TokenKind int
+
+const (
+	// EOF is the end of the source.
+	EOF TokenKind = iota
+	// Name is an identifier.
+	Name
+	// Keyword is a reserved word, such as function or and.
+	Keyword
+	// Number is a numeric literal.
+	Number
+	// String is a quoted or long bracket string literal.
+	String
+	// Symbol is an operator or punctuation, such as == or (.
+	Symbol
+)
+
+// Token is a single token of the source.
+type Token struct {
+	Kind TokenKind
+	// Text is the source text of the token, or the decoded value of a
+	// String.
+	Text string
+	Pos  Pos
+	End  Pos
+}
+
+func (t Token) String() string {
+	switch t.Kind {
+	case EOF:
+		return "<eof>"
+	case String:
+		return fmt.Sprintf("%q", t.Text)
+	default:
+		return "'" + t.Text + "'"
+	}
+}
+
+// Comment is a comment in the source.
+type Comment struct {
+	// Text is the source text of the comment, including the leading --.
+	Text string
+	Pos  Pos
+	End  Pos
+	// Trailing is true when the comment follows code on the same line.
+	Trailing bool
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true,
+	"end": true, "false": true, "for": true, "function": true, "if": true,
+	"in": true, "local": true, "nil": true, "not": true, "or": true,
+	"repeat": true, "return": true, "then": true, "true": true,
+	"until": true, "while": true,
+}
+
+// symbols lists the operators and punctuation, longest first.
+var symbols = []string{
+	"...", "..", "==", "~=", "<=", ">=",
+	"+", "-", "*", "/", "%", "^", "#", "<", ">", "=",
+	"(", ")", "{", "}", "[", "]", ";", ":", ",", ".",
+}
+
+type lexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+	// lastLine is the line the last token ended on, 0 before the first.
+	lastLine int
+	comments []*Comment
+}
+
+func newLexer(src string) *lexer {
+	l := &lexer{src: src, line: 1, col: 1}
+	// A first line starting with # is a shebang and is skipped, like the
+	// standalone interpreter does.
+	if strings.HasPrefix(src, "#") {
+		for l.off < len(l.src) && l.src[l.off] != '\n' {
+			l.advance(1)
+		}
+	}
+	return l
+}
+
+// Tokenize splits Lua source into its tokens, without the final EOF, and
+// returns them with the comments of the source, both in source order.
+func Tokenize(src []byte) ([]Token, []*Comment, error) {
+	l := newLexer(string(src))
+	var toks []Token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if tok.Kind == EOF {
+			return toks, l.comments, nil
+		}
+		toks = append(toks, tok)
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Offset: l.off, Line: l.line, Column: l.col}
+}
+
+func (l *lexer) errorf(pos Pos, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// advance moves n bytes forward, keeping track of lines.
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.off < len(l.src); i++ {
+		if l.src[l.off] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.off++
+	}
+}
+
+func (l *lexer) peekByte(i int) byte {
+	if l.off+i < len(l.src) {
+		return l.src[l.off+i]
+	}
+	return 0
+}
+
+// next returns the next token, collecting the comments before it.
+func (l *lexer) next() (Token, error) {
+	if err := l.skip(); err != nil {
+		return Token{}, err
+	}
+	start := l.pos()
+	if l.off >= len(l.src) {
+		return Token{Kind: EOF, Pos: start, End: start}, nil
+	}
+
+	var tok Token
+	var err error
+	c := l.src[l.off]
+	switch {
+	case isLetter(c):
+		for l.off < len(l.src) && (isLetter(l.src[l.off]) || isDigit(l.src[l.off])) {
+			l.advance(1)
+		}
+		text := l.src[start.Offset:l.off]
+		kind := Name
+		if keywords[text] {
+			kind = Keyword
+		}
+		tok = Token{Kind: kind, Text: text}
+	case isDigit(c) || (c == '.' && isDigit(l.peekByte(1))):
+		l.number()
+		tok = Token{Kind: Number, Text: l.src[start.Offset:l.off]}
+	case c == '"' || c == '\'':
+		tok, err = l.quoted(c)
+	case c == '[' && l.longBracket() >= 0:
+		var text string
+		text, err = l.long(l.longBracket())
+		tok = Token{Kind: String, Text: text}
+	default:
+		for _, sym := range symbols {
+			if strings.HasPrefix(l.src[l.off:], sym) {
+				l.advance(len(sym))
+				tok = Token{Kind: Symbol, Text: sym}
+				break
+			}
+		}
+		if tok.Text == "" {
+			return Token{}, l.errorf(start, "unexpected symbol %q", c)
+		}
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	tok.Pos, tok.End = start, l.pos()
+	l.lastLine = l.line
+	return tok, nil
+}
+
+// skip skips white space and collects comments.
+func (l *lexer) skip() error {
+	for l.off < len(l.src) {
+		switch c := l.src[l.off]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v':
+			l.advance(1)
+		case strings.HasPrefix(l.src[l.off:], "--"):
+			start := l.pos()
+			l.advance(2)
+			if level := l.longBracket(); level >= 0 {
+				if _, err := l.long(level); err != nil {
+					return l.errorf(start, "unfinished long comment")
+				}
+			} else {
+				for l.off < len(l.src) && l.src[l.off] != '\n' {
+					l.advance(1)
+				}
+			}
+			l.comments = append(l.comments, &Comment{
+				Text:     strings.TrimRight(l.src[start.Offset:l.off], "\r"),
+				Pos:      start,
+				End:      l.pos(),
+				Trailing: l.lastLine == start.Line,
+			})
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// number reads a numeral the way Lua 5.1 does: digits and dots, an
+// optional exponent, and then any letters or digits, so that malformed
+// numbers are one token. A hex numeral has no exponent, so 0x1E+1 is
+// 0x1E + 1.
+func (l *lexer) number() {
+	hex := l.peekByte(0) == '0' && (l.peekByte(1) == 'x' || l.peekByte(1) == 'X')
+	if hex {
+		l.advance(2)
+	}
+	for l.off < len(l.src) && (isDigit(l.src[l.off]) || l.src[l.off] == '.') {
+		l.advance(1)
+	}
+	if c := l.peekByte(0); !hex && (c == 'e' || c == 'E') {
+		l.advance(1)
+		if c := l.peekByte(0); c == '+' || c == '-' {
+			l.advance(1)
+		}
+	}
+	for l.off < len(l.src) && (isLetter(l.src[l.off]) || isDigit(l.src[l.off])) {
+		l.advance(1)
+	}
+}
+
+// longBracket returns the level of the long bracket that opens at the
+// current position, e.g. 2 for [==[, or -1 if there is none.
+func (l *lexer) longBracket() int {
+	if l.peekByte(0) != '[' {
+		return -1
+	}
+	level := 0
+	for l.peekByte(level+1) == '=' {
+		level++
+	}
+	if l.peekByte(level+1) != '[' {
+		return -1
+	}
+	return level
+}
+
+// long reads a long bracket string or comment of the given level and
+// returns its content. A newline right after the opening bracket is not
+// part of the content.
+func (l *lexer) long(level int) (string, error) {
+	start := l.pos()
+	l.advance(level + 2)
+	if l.peekByte(0) == '\r' {
+		l.advance(1)
+	}
+	if l.peekByte(0) == '\n' {
+		l.advance(1)
+	}
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(l.src[l.off:], closing)
+	if end == -1 {
+		return "", l.errorf(start, "unfinished long string")
+	}
+	content := l.src[l.off : l.off+end]
+	l.advance(end + len(closing))
+	return content, nil
+}
+
+// quoted reads a string in quotes and decodes its escape sequences.
+func (l *lexer) quoted(quote byte) (Token, error) {
+	start := l.pos()
+	l.advance(1)
+	var b strings.Builder
+	for {
+		if l.off >= len(l.src) || l.src[l.off] == '\n' {
+			return Token{}, l.errorf(start, "unfinished string")
+		}
+		c := l.src[l.off]
+		if c == quote {
+			l.advance(1)
+			return Token{Kind: String, Text: b.String()}, nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			l.advance(1)
+			continue
+		}
+
+		l.advance(1)
+		e := l.peekByte(0)
+		switch e {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\n':
+			b.WriteByte('\n')
+		case 0:
+			return Token{}, l.errorf(start, "unfinished string")
+		default:
+			if !isDigit(e) {
+				// \\, \", \' and any other escaped character stand for
+				// themselves.
+				b.WriteByte(e)
+				break
+			}
+			n := 0
+			for i := 0; i < 3 && isDigit(l.peekByte(0)); i++ {
+				n = n*10 + int(l.peekByte(0)-'0')
+				l.advance(1)
+			}
+			if n > 255 {
+				return Token{}, l.errorf(start, "escape sequence too large")
+			}
+			b.WriteByte(byte(n))
+			continue
+		}
+		l.advance(1)
+	}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
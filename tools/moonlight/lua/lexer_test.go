@@ -0,0 +1,80 @@
+package lua
+
+import (
+	"slices"
+	"testing"
+)
+
+// lex returns the tokens of src, and the texts of its comments.
+func lex(t *testing.T, src string) ([]Token, []string) {
+	t.Helper()
+	l := newLexer(src)
+	var toks []Token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			t.Fatalf("lexing %q: %v", src, err)
+		}
+		if tok.Kind == EOF {
+			break
+		}
+		toks = append(toks, tok)
+	}
+	var comments []string
+	for _, c := range l.comments {
+		comments = append(comments, c.Text)
+	}
+	return toks, comments
+}
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		tokens   []string
+		comments []string
+	}{
+		{"long string", `[[a "b" c]]`, []string{`"a \"b\" c"`}, nil},
+		{"long string level", `[==[a]]b]=]c]==]`, []string{`"a]]b]=]c"`}, nil},
+		{"long string first newline", "[[\nline\n]]", []string{`"line\n"`}, nil},
+		{"index is not a long string", `t[ [=[k]=] ]`, []string{`'t'`, `'['`, `"k"`, `']'`}, nil},
+		{"line comment", "a -- b [[c\nd", []string{`'a'`, `'d'`}, []string{"-- b [[c"}},
+		{"long comment", "a --[[b\nc]] d", []string{`'a'`, `'d'`}, []string{"--[[b\nc]]"}},
+		{"long comment level", "--[=[ ]] ]=] e", []string{`'e'`}, []string{"--[=[ ]] ]=]"}},
+		{"escapes", `"a\tb\n\\\"\'"`, []string{`"a\tb\n\\\"'"`}, nil},
+		{"decimal escapes", `'\65\0661\x'`, []string{`"AB1x"`}, nil},
+		{"escaped newline", "\"a\\\nb\"", []string{`"a\nb"`}, nil},
+		{"numbers", "0x1F 1e-3 .5 3..4", []string{`'0x1F'`, `'1e-3'`, `'.5'`, `'3..4'`}, nil},
+		{"hex numbers have no exponent", "0x1E+1 0XeE-2", []string{`'0x1E'`, `'+'`, `'1'`, `'0XeE'`, `'-'`, `'2'`}, nil},
+		{"symbols", "a...b..c~=d", []string{`'a'`, `'...'`, `'b'`, `'..'`, `'c'`, `'~='`, `'d'`}, nil},
+		{"shebang", "#!/usr/bin/lua\nx", []string{`'x'`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toks, comments := lex(t, tt.src)
+			var got []string
+			for _, tok := range toks {
+				got = append(got, tok.String())
+			}
+			if !slices.Equal(got, tt.tokens) {
+				t.Errorf("tokens = %v, want %v", got, tt.tokens)
+			}
+			if !slices.Equal(comments, tt.comments) {
+				t.Errorf("comments = %q, want %q", comments, tt.comments)
+			}
+		})
+	}
+}
+
+func TestLexerPositions(t *testing.T) {
+	toks, _ := lex(t, "a = [[\nx]] .. 'y'\n  b")
+	want := []Pos{{0, 1, 1}, {2, 1, 3}, {4, 1, 5}, {11, 2, 5}, {14, 2, 8}, {20, 3, 3}}
+	if len(toks) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(toks), len(want))
+	}
+	for i, tok := range toks {
+		if tok.Pos != want[i] {
+			t.Errorf("%s is at %+v, want %+v", tok, tok.Pos, want[i])
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package lua
+
+import (
+	"fmt"
+	"slices"
+)
+
+// binaryPriority holds the left and right priority of every binary
+// operator, as in the Lua 5.1 parser. A right priority lower than the left
+// one makes the operator right associative.
+var binaryPriority = map[string][2]int{
+	"or":  {1, 1},
+	"and": {2, 2},
+	"<":   {3, 3}, ">": {3, 3}, "<=": {3, 3}, ">=": {3, 3}, "~=": {3, 3}, "==": {3, 3},
+	"..": {5, 4},
+	"+":  {6, 6}, "-": {6, 6},
+	"*": {7, 7}, "/": {7, 7}, "%": {7, 7},
+	"^": {10, 9},
+}
+
+// unaryPriority is the priority of not, - and #.
+const unaryPriority = 8
+
+type parser struct {
+	lx  *lexer
+	tok Token
+	// ahead is the token after tok, once peek has read it.
+	ahead *Token
+	// prevEnd is the end of the token before tok.
+	prevEnd Pos
+	// comment is the index of the first comment that has not been
+	// considered for a statement yet.
+	comment int
+}
+
+// bailout carries a syntax error up to Parse.
+type bailout struct{ err error }
+
+// Parse parses a Lua 5.1 source file.
+func Parse(src []byte) (chunk *Chunk, err error) {
+	p := &parser{lx: newLexer(string(src))}
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			chunk, err = nil, b.err
+		}
+	}()
+
+	p.next()
+	block := p.block()
+	if p.tok.Kind != EOF {
+		p.failf("'<eof>' expected near %s", p.tok)
+	}
+	return &Chunk{Block: block, Comments: p.lx.comments}, nil
+}
+
+func (p *parser) failf(format string, args ...any) {
+	panic(bailout{&Error{Pos: p.tok.Pos, Msg: fmt.Sprintf(format, args...)}})
+}
+
+func (p *parser) next() {
+	p.prevEnd = p.tok.End
+	if p.ahead != nil {
+		p.tok, p.ahead = *p.ahead, nil
+		return
+	}
+	tok, err := p.lx.next()
+	if err != nil {
+		panic(bailout{err})
+	}
+	p.tok = tok
+}
+
+func (p *parser) peek() Token {
+	if p.ahead == nil {
+		tok, err := p.lx.next()
+		if err != nil {
+			panic(bailout{err})
+		}
+		p.ahead = &tok
+	}
+	return *p.ahead
+}
+
+// is reports if the current token is the keyword or symbol text.
+func (p *parser) is(text string) bool {
+	return (p.tok.Kind == Keyword || p.tok.Kind == Symbol) && p.tok.Text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) {
+	if !p.accept(text) {
+		p.failf("'%s' expected near %s", text, p.tok)
+	}
+}
+
+// expectClosing expects the keyword that closes the block opened by open
+// at start, naming both in the error like Lua does.
+func (p *parser) expectClosing(text, open string, start Pos) {
+	if p.is(text) {
+		p.next()
+		return
+	}
+	if start.Line == p.tok.Pos.Line {
+		p.failf("'%s' expected near %s", text, p.tok)
+	}
+	p.failf("'%s' expected (to close '%s' at line %d) near %s", text, open, start.Line, p.tok)
+}
+
+func (p *parser) ident() *Ident {
+	if p.tok.Kind != Name {
+		p.failf("<name> expected near %s", p.tok)
+	}
+	id := &Ident{span: span{p.tok.Pos, p.tok.End}, Name: p.tok.Text}
+	p.next()
+	return id
+}
+
+// blockEnd reports if the current token ends a block.
+func (p *parser) blockEnd() bool {
+	if p.tok.Kind == EOF {
+		return true
+	}
+	return p.tok.Kind == Keyword && slices.Contains([]string{"else", "elseif", "end", "until"}, p.tok.Text)
+}
+
+// doc returns the comments that belong to a statement starting at start:
+// the group of comments right above it, with no blank line in between,
+// that are not trailing comments of earlier code.
+func (p *parser) doc(start Pos) []*Comment {
+	comments := p.lx.comments
+	end := p.comment
+	for end < len(comments) && comments[end].Pos.Offset < start.Offset {
+		end++
+	}
+	first, line := end, start.Line
+	for first > p.comment {
+		c := comments[first-1]
+		if c.Trailing || c.End.Line < line-1 {
+			break
+		}
+		first--
+		line = c.Pos.Line
+	}
+	p.comment = end
+	return slices.Clone(comments[first:end])
+}
+
+func (p *parser) block() *Block {
+	b := &Block{span: span{p.tok.Pos, p.tok.Pos}}
+	for !p.blockEnd() {
+		if p.accept(";") {
+			continue
+		}
+		s := p.statement()
+		b.Stmts = append(b.Stmts, s)
+		b.Stop = s.End()
+		if _, ok := s.(*Return); ok {
+			break
+		}
+	}
+	return b
+}
+
+func (p *parser) statement() Stmt {
+	start := p.tok.Pos
+	doc := p.doc(start)
+
+	var s Stmt
+	switch {
+	case p.is("if"):
+		s = p.ifStmt()
+	case p.is("while"):
+		p.next()
+		cond := p.expr()
+		p.expect("do")
+		body := p.block()
+		p.expectClosing("end", "while", start)
+		s = &While{Cond: cond, Body: body}
+	case p.is("do"):
+		p.next()
+		body := p.block()
+		p.expectClosing("end", "do", start)
+		s = &Do{Body: body}
+	case p.is("for"):
+		s = p.forStmt(start)
+	case p.is("repeat"):
+		p.next()
+		body := p.block()
+		p.expectClosing("until", "repeat", start)
+		s = &Repeat{Body: body, Cond: p.expr()}
+	case p.is("function"):
+		p.next()
+		name := p.funcName()
+		s = &FunctionStmt{Name: name, Func: p.funcBody(start)}
+	case p.is("local"):
+		p.next()
+		if p.accept("function") {
+			name := p.ident()
+			s = &LocalFunction{Name: name, Func: p.funcBody(start)}
+			break
+		}
+		local := &LocalAssign{}
+		for {
+			local.Names = append(local.Names, p.ident())
+			if !p.accept(",") {
+				break
+			}
+		}
+		if p.accept("=") {
+			local.Values = p.exprList()
+		}
+		s = local
+	case p.is("return"):
+		p.next()
+		ret := &Return{}
+		if !p.blockEnd() && !p.is(";") {
+			ret.Values = p.exprList()
+		}
+		p.accept(";")
+		s = ret
+	case p.is("break"):
+		p.next()
+		s = &Break{}
+	default:
+		s = p.exprStmt()
+	}
+	s.init(start, p.prevEnd, doc)
+	return s
+}
+
+func (p *parser) ifStmt() Stmt {
+	start := p.tok.Pos
+	s := &If{}
+	for p.is("if") || p.is("elseif") {
+		p.next()
+		cond := p.expr()
+		p.expect("then")
+		s.Clauses = append(s.Clauses, &IfClause{Cond: cond, Body: p.block()})
+		if !p.is("elseif") {
+			break
+		}
+	}
+	if p.accept("else") {
+		s.Else = p.block()
+	}
+	p.expectClosing("end", "if", start)
+	return s
+}
+
+func (p *parser) forStmt(start Pos) Stmt {
+	p.next()
+	first := p.ident()
+	if p.accept("=") {
+		s := &NumericFor{Var: first, Start: p.expr()}
+		p.expect(",")
+		s.Limit = p.expr()
+		if p.accept(",") {
+			s.Step = p.expr()
+		}
+		p.expect("do")
+		s.Body = p.block()
+		p.expectClosing("end", "for", start)
+		return s
+	}
+
+	s := &GenericFor{Names: []*Ident{first}}
+	for p.accept(",") {
+		s.Names = append(s.Names, p.ident())
+	}
+	p.expect("in")
+	s.Exprs = p.exprList()
+	p.expect("do")
+	s.Body = p.block()
+	p.expectClosing("end", "for", start)
+	return s
+}
+
+func (p *parser) funcName() *FuncName {
+	n := &FuncName{span: span{Start: p.tok.Pos}}
+	n.Path = append(n.Path, p.ident())
+	for p.accept(".") {
+		n.Path = append(n.Path, p.ident())
+	}
+	if p.accept(":") {
+		n.Method = p.ident()
+	}
+	n.Stop = p.prevEnd
+	return n
+}
+
+// funcBody parses the parameters and body of a function that starts at
+// start, after its name.
+func (p *parser) funcBody(start Pos) *Function {
+	f := &Function{}
+	p.expect("(")
+	for !p.is(")") {
+		if p.accept("...") {
+			f.Vararg = true
+			break
+		}
+		f.Params = append(f.Params, p.ident())
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(")")
+	f.Body = p.block()
+	p.expectClosing("end", "function", start)
+	f.span = span{start, p.prevEnd}
+	return f
+}
+
+func (p *parser) exprStmt() Stmt {
+	e := p.suffixedExpr()
+	if !p.is("=") && !p.is(",") {
+		switch e.(type) {
+		case *Call, *MethodCall:
+			return &CallStmt{Call: e}
+		}
+		p.failf("syntax error near %s", p.tok)
+	}
+
+	s := &Assign{Targets: []Expr{e}}
+	for p.accept(",") {
+		s.Targets = append(s.Targets, p.suffixedExpr())
+	}
+	for _, target := range s.Targets {
+		switch target.(type) {
+		case *Ident, *Field, *Index:
+		default:
+			p.failf("syntax error near %s, can not assign to this expression", p.tok)
+		}
+	}
+	p.expect("=")
+	s.Values = p.exprList()
+	return s
+}
+
+func (p *parser) exprList() []Expr {
+	list := []Expr{p.expr()}
+	for p.accept(",") {
+		list = append(list, p.expr())
+	}
+	return list
+}
+
+func (p *parser) expr() Expr {
+	return p.subExpr(0)
+}
+
+// subExpr parses an expression whose binary operators bind tighter than
+// limit.
+func (p *parser) subExpr(limit int) Expr {
+	start := p.tok.Pos
+	var e Expr
+	if p.is("not") || p.is("-") || p.is("#") {
+		op := p.tok.Text
+		p.next()
+		x := p.subExpr(unaryPriority)
+		e = &Unary{span: span{start, p.prevEnd}, Op: op, X: x}
+	} else {
+		e = p.simpleExpr()
+	}
+
+	for p.tok.Kind == Keyword || p.tok.Kind == Symbol {
+		op := p.tok.Text
+		priority, ok := binaryPriority[op]
+		if !ok || priority[0] <= limit {
+			break
+		}
+		p.next()
+		y := p.subExpr(priority[1])
+		e = &Binary{span: span{start, p.prevEnd}, Op: op, X: e, Y: y}
+	}
+	return e
+}
+
+func (p *parser) simpleExpr() Expr {
+	tok := p.tok
+	sp := span{tok.Pos, tok.End}
+	switch {
+	case tok.Kind == Number:
+		p.next()
+		return &NumberLit{span: sp, Raw: tok.Text}
+	case tok.Kind == String:
+		p.next()
+		return &StringLit{span: sp, Value: tok.Text}
+	case p.is("nil"):
+		p.next()
+		return &Nil{span: sp}
+	case p.is("true"), p.is("false"):
+		p.next()
+		return &Bool{span: sp, Value: tok.Text == "true"}
+	case p.is("..."):
+		p.next()
+		return &Vararg{span: sp}
+	case p.is("{"):
+		return p.table()
+	case p.is("function"):
+		p.next()
+		return p.funcBody(tok.Pos)
+	}
+	return p.suffixedExpr()
+}
+
+func (p *parser) primaryExpr() Expr {
+	start := p.tok.Pos
+	switch {
+	case p.tok.Kind == Name:
+		return p.ident()
+	case p.is("("):
+		p.next()
+		x := p.expr()
+		p.expect(")")
+		return &Paren{span: span{start, p.prevEnd}, X: x}
+	}
+	p.failf("unexpected symbol near %s", p.tok)
+	return nil
+}
+
+func (p *parser) suffixedExpr() Expr {
+	start := p.tok.Pos
+	e := p.primaryExpr()
+	for {
+		switch {
+		case p.is("."):
+			p.next()
+			name := p.ident()
+			e = &Field{span: span{start, p.prevEnd}, X: e, Name: name}
+		case p.is("["):
+			p.next()
+			key := p.expr()
+			p.expect("]")
+			e = &Index{span: span{start, p.prevEnd}, X: e, Key: key}
+		case p.is(":"):
+			p.next()
+			method := p.ident()
+			args := p.args()
+			e = &MethodCall{span: span{start, p.prevEnd}, Recv: e, Method: method, Args: args}
+		case p.is("("), p.is("{"), p.tok.Kind == String:
+			args := p.args()
+			e = &Call{span: span{start, p.prevEnd}, Func: e, Args: args}
+		default:
+			return e
+		}
+	}
+}
+
+func (p *parser) args() []Expr {
+	switch {
+	case p.tok.Kind == String:
+		tok := p.tok
+		p.next()
+		return []Expr{&StringLit{span: span{tok.Pos, tok.End}, Value: tok.Text}}
+	case p.is("{"):
+		return []Expr{p.table()}
+	case p.is("("):
+		p.next()
+		var args []Expr
+		if !p.is(")") {
+			args = p.exprList()
+		}
+		p.expect(")")
+		return args
+	}
+	p.failf("function arguments expected near %s", p.tok)
+	return nil
+}
+
+func (p *parser) table() Expr {
+	start := p.tok.Pos
+	p.expect("{")
+	t := &Table{}
+	for !p.is("}") {
+		field := &TableField{}
+		switch {
+		case p.is("["):
+			p.next()
+			field.Key = p.expr()
+			p.expect("]")
+			p.expect("=")
+		case p.tok.Kind == Name && p.peek().Kind == Symbol && p.peek().Text == "=":
+			field.Key = p.ident()
+			p.next()
+		}
+		field.Value = p.expr()
+		t.Fields = append(t.Fields, field)
+		if !p.accept(",") && !p.accept(";") {
+			break
+		}
+	}
+	if !p.is("}") {
+		p.failf("'}' expected (to close '{' at line %d) near %s", start.Line, p.tok)
+	}
+	p.next()
+	t.span = span{start, p.prevEnd}
+	return t
+}
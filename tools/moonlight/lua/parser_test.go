@@ -0,0 +1,252 @@
+package lua
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sexpr renders e with every operator and call in parentheses, so that
+// tests can compare the shape of the tree.
+func sexpr(e Expr) string {
+	list := func(exprs []Expr) string {
+		var parts []string
+		for _, x := range exprs {
+			parts = append(parts, sexpr(x))
+		}
+		return strings.Join(parts, " ")
+	}
+	switch e := e.(type) {
+	case *Ident:
+		return e.Name
+	case *Nil:
+		return "nil"
+	case *Bool:
+		return fmt.Sprint(e.Value)
+	case *NumberLit:
+		return e.Raw
+	case *StringLit:
+		return fmt.Sprintf("%q", e.Value)
+	case *Vararg:
+		return "..."
+	case *Function:
+		return "function"
+	case *Table:
+		var fields []string
+		for _, f := range e.Fields {
+			switch key := f.Key.(type) {
+			case nil:
+				fields = append(fields, sexpr(f.Value))
+			case *Ident:
+				fields = append(fields, key.Name+"="+sexpr(f.Value))
+			default:
+				fields = append(fields, "["+sexpr(f.Key)+"]="+sexpr(f.Value))
+			}
+		}
+		return "{" + strings.Join(fields, " ") + "}"
+	case *Binary:
+		return "(" + e.Op + " " + sexpr(e.X) + " " + sexpr(e.Y) + ")"
+	case *Unary:
+		return "(" + e.Op + " " + sexpr(e.X) + ")"
+	case *Field:
+		return sexpr(e.X) + "." + e.Name.Name
+	case *Index:
+		return sexpr(e.X) + "[" + sexpr(e.Key) + "]"
+	case *Call:
+		return "(call " + sexpr(e.Func) + " " + list(e.Args) + ")"
+	case *MethodCall:
+		return "(method " + sexpr(e.Recv) + " " + e.Method.Name + " " + list(e.Args) + ")"
+	case *Paren:
+		return "(paren " + sexpr(e.X) + ")"
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+func parse(t *testing.T, src string) *Chunk {
+	t.Helper()
+	chunk, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	return chunk
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"1 - 2 - 3", "(- (- 1 2) 3)"},
+		{"-2^-2", "(- (^ 2 (- 2)))"},
+		{"2^3^2", "(^ 2 (^ 3 2))"},
+		{"a..b..c", "(.. a (.. b c))"},
+		{"a .. b + c", "(.. a (+ b c))"},
+		{"not a == b", "(== (not a) b)"},
+		{"#t + 1", "(+ (# t) 1)"},
+		{"a or b and c", "(or a (and b c))"},
+		{"a < b == c", "(== (< a b) c)"},
+		{"(a + b) * c", "(* (paren (+ a b)) c)"},
+		{`a.b:c"s"`, `(method a.b c "s")`},
+		{"f{1, x = 2, [3] = 4}", "(call f {1 x=2 [3]=4})"},
+		{"f[[s]]", `(call f "s")`},
+		{`f"a""b"`, `(call (call f "a") "b")`},
+		{"a.b[c](d):e(f, g).h", "(method (call a.b[c] d) e f g).h"},
+		{"function(...) end", "function"},
+		{"{...; nil, true}", "{... nil true}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			chunk := parse(t, "x = "+tt.src)
+			assign, ok := chunk.Block.Stmts[0].(*Assign)
+			if !ok {
+				t.Fatalf("parsed a %T, want an assignment", chunk.Block.Stmts[0])
+			}
+			if got := sexpr(assign.Values[0]); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStmt(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"local a, b = 1", "*lua.LocalAssign"},
+		{"a.b, c[1] = 1, 2", "*lua.Assign"},
+		{`print "x"`, "*lua.CallStmt"},
+		{"obj:Method()", "*lua.CallStmt"},
+		{"do end", "*lua.Do"},
+		{"while x do break end", "*lua.While"},
+		{"repeat local x until x", "*lua.Repeat"},
+		{"if a then elseif b then else end", "*lua.If"},
+		{"for i = 1, 10, 2 do end", "*lua.NumericFor"},
+		{"for k, v in pairs(t) do end", "*lua.GenericFor"},
+		{"function a.b:c(x, ...) end", "*lua.FunctionStmt"},
+		{"local function f() end", "*lua.LocalFunction"},
+		{"return 1, 2;", "*lua.Return"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			chunk := parse(t, tt.src)
+			if len(chunk.Block.Stmts) != 1 {
+				t.Fatalf("parsed %d statements, want 1", len(chunk.Block.Stmts))
+			}
+			if got := fmt.Sprintf("%T", chunk.Block.Stmts[0]); got != tt.want {
+				t.Errorf("parsed a %s, want a %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuncName(t *testing.T) {
+	chunk := parse(t, "function a.b:c(x, ...) end")
+	fn := chunk.Block.Stmts[0].(*FunctionStmt)
+	if got := fn.Name.String(); got != "a.b:c" {
+		t.Errorf("name = %s, want a.b:c", got)
+	}
+	if len(fn.Func.Params) != 1 || fn.Func.Params[0].Name != "x" || !fn.Func.Vararg {
+		t.Errorf("params = %v vararg %v, want x and a vararg", fn.Func.Params, fn.Func.Vararg)
+	}
+}
+
+func TestDoc(t *testing.T) {
+	src := `-- file header
+
+---@class A
+---@field x number
+A = {}
+B = {} -- trailing
+C = {}
+--[[ long
+comment ]]
+D = {}
+-- detached
+
+E = {}
+if true then
+	-- nested
+	F = {}
+end
+`
+	chunk := parse(t, src)
+	var got []string
+	Inspect(chunk.Block, func(n Node) bool {
+		assign, ok := n.(*Assign)
+		if !ok {
+			return true
+		}
+		var texts []string
+		for _, c := range assign.Doc() {
+			texts = append(texts, c.Text)
+		}
+		name, _ := DottedName(assign.Targets[0])
+		got = append(got, name+": "+strings.Join(texts, " | "))
+		return true
+	})
+	want := []string{
+		"A: ---@class A | ---@field x number",
+		"B: ",
+		"C: ",
+		"D: --[[ long\ncomment ]]",
+		"E: ",
+		"F: -- nested",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("docs =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(chunk.Comments) != 7 {
+		t.Errorf("chunk has %d comments, want 7", len(chunk.Comments))
+	}
+}
+
+func TestPositions(t *testing.T) {
+	chunk := parse(t, "local x = 1\n\tfoo.bar(x)\n")
+	call := chunk.Block.Stmts[1]
+	if pos := call.Pos(); pos != (Pos{Offset: 13, Line: 2, Column: 2}) {
+		t.Errorf("call starts at %+v, want 2:2", pos)
+	}
+	if end := call.End(); end != (Pos{Offset: 23, Line: 2, Column: 12}) {
+		t.Errorf("call ends at %+v, want 2:12", end)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"x = (", "1:6: unexpected symbol near <eof>"},
+		{"x = 1 +\n\n* 2", "3:1: unexpected symbol near '*'"},
+		{"f(", "1:3: unexpected symbol near <eof>"},
+		{"x", "1:2: syntax error near <eof>"},
+		{"f() = 1", "1:5: syntax error near '=', can not assign to this expression"},
+		{"if x then\n\nelse", "3:5: 'end' expected (to close 'if' at line 1) near <eof>"},
+		{"while x do", "1:11: 'end' expected near <eof>"},
+		{"function f(a, 1) end", "1:15: <name> expected near '1'"},
+		{"t = {1, 2\nx = 3", "2:1: '}' expected (to close '{' at line 1) near 'x'"},
+		{"return 1 x = 2", "1:10: '<eof>' expected near 'x'"},
+		{"x = 'abc\n'", "1:5: unfinished string"},
+		{"x = '\\300'", "1:5: escape sequence too large"},
+		{"x = 1\n--[==[ never\nclosed ]]", "2:1: unfinished long comment"},
+		{"x = [=[ never\nclosed ]]", "1:5: unfinished long string"},
+		{"x = @", "1:5: unexpected symbol '@'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil {
+				t.Fatal("parsed, want an error")
+			}
+			if _, ok := err.(*Error); !ok {
+				t.Errorf("error is a %T, want an *Error", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
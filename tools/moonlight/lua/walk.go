@@ -0,0 +1,101 @@
+package lua
+
+// Inspect walks the tree rooted at node in source order, calling f for
+// every node. If f returns false, the children of the node are skipped.
+// Blocks are visited as well, as *Block nodes.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *Block:
+		for _, s := range n.Stmts {
+			Inspect(s, f)
+		}
+
+	case *LocalAssign:
+		for _, name := range n.Names {
+			Inspect(name, f)
+		}
+		inspectExprs(n.Values, f)
+	case *Assign:
+		inspectExprs(n.Targets, f)
+		inspectExprs(n.Values, f)
+	case *CallStmt:
+		Inspect(n.Call, f)
+	case *Do:
+		Inspect(n.Body, f)
+	case *While:
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+	case *Repeat:
+		Inspect(n.Body, f)
+		Inspect(n.Cond, f)
+	case *If:
+		for _, clause := range n.Clauses {
+			Inspect(clause.Cond, f)
+			Inspect(clause.Body, f)
+		}
+		if n.Else != nil {
+			Inspect(n.Else, f)
+		}
+	case *NumericFor:
+		Inspect(n.Var, f)
+		Inspect(n.Start, f)
+		Inspect(n.Limit, f)
+		if n.Step != nil {
+			Inspect(n.Step, f)
+		}
+		Inspect(n.Body, f)
+	case *GenericFor:
+		for _, name := range n.Names {
+			Inspect(name, f)
+		}
+		inspectExprs(n.Exprs, f)
+		Inspect(n.Body, f)
+	case *FunctionStmt:
+		Inspect(n.Func, f)
+	case *LocalFunction:
+		Inspect(n.Name, f)
+		Inspect(n.Func, f)
+	case *Return:
+		inspectExprs(n.Values, f)
+
+	case *Function:
+		for _, param := range n.Params {
+			Inspect(param, f)
+		}
+		Inspect(n.Body, f)
+	case *Table:
+		for _, field := range n.Fields {
+			if field.Key != nil {
+				Inspect(field.Key, f)
+			}
+			Inspect(field.Value, f)
+		}
+	case *Binary:
+		Inspect(n.X, f)
+		Inspect(n.Y, f)
+	case *Unary:
+		Inspect(n.X, f)
+	case *Field:
+		Inspect(n.X, f)
+	case *Index:
+		Inspect(n.X, f)
+		Inspect(n.Key, f)
+	case *Call:
+		Inspect(n.Func, f)
+		inspectExprs(n.Args, f)
+	case *MethodCall:
+		Inspect(n.Recv, f)
+		inspectExprs(n.Args, f)
+	case *Paren:
+		Inspect(n.X, f)
+	}
+}
+
+func inspectExprs(exprs []Expr, f func(Node) bool) {
+	for _, e := range exprs {
+		Inspect(e, f)
+	}
+}
@@ -0,0 +1,145 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/boot"
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+func newDeleteCmd() *cobra.Command {
+	var force bool
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "delete [path]",
+		Short: "Delete a Moonlight module",
+		Long: `Deletes an existing Moonlight module at the specified repo URI path (e.g. //folder/MyModule.lua).
+The module file, its accessor in boot/boot.lua, its Boot() call in Moonlight:Start and its
+Moonlight.toc entry are all removed, along with the XML file next to it that loads it, if any.
+The command refuses to run while other files still call the module accessor, unless --force
+is given, and while that XML file loads other files as well.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mp, err := resolveModulePath(args[0])
+			if err != nil {
+				return err
+			}
+
+			content, err := os.ReadFile(mp.Abs)
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no module exists at: %s", mp.Abs)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read module file: %w", err)
+			}
+
+			// Accessors are named after the registered class, which does not
+			// always match the file name (e.g. sonata/engine.lua -> sonataEngine).
+			var modules []moduleNames
+			for _, class := range scan.Classes(content) {
+				n, err := namesFromBase(class)
+				if err != nil {
+					return err
+				}
+				modules = append(modules, n)
+			}
+			if len(modules) == 0 {
+				n, err := namesFromBase(mp.baseName())
+				if err != nil {
+					return err
+				}
+				modules = append(modules, n)
+			}
+
+			bootPath, err := util.GetRepoPath(boot.Path)
+			if err != nil {
+				return fmt.Errorf("failed to get boot path: %w", err)
+			}
+			bootSrc, err := os.ReadFile(bootPath)
+			if err != nil {
+				return fmt.Errorf("failed to read boot file: %w", err)
+			}
+
+			var callSites []string
+			for _, n := range modules {
+				sites, err := scan.AccessorCalls(mp.Root, n.Accessor(), mp.Rel, strings.TrimPrefix(boot.Path, "//"))
+				if err != nil {
+					return err
+				}
+				for _, site := range sites {
+					callSites = append(callSites, site.String())
+				}
+				callSites = append(callSites, boot.StartUsages(bootSrc, n.Accessor())...)
+			}
+			if len(callSites) > 0 && !force {
+				return fmt.Errorf("module is still in use, remove these call sites or use --force:\n  %s", strings.Join(callSites, "\n  "))
+			}
+
+			var removed []string
+			for _, n := range modules {
+				var ok bool
+				if bootSrc, ok = boot.RemoveAccessor(bootSrc, n.Accessor()); ok {
+					removed = append(removed, fmt.Sprintf("accessor Moonlight:%s() from %s", n.Accessor(), boot.Path))
+				}
+				if bootSrc, ok = boot.RemoveBoot(bootSrc, n.Accessor()); ok {
+					removed = append(removed, fmt.Sprintf("%s Boot() call from Moonlight:Start", n.ModuleNameLower))
+				}
+			}
+
+			tocPath := filepath.Join(mp.Root, "Moonlight.toc")
+			tocFile, err := toc.Load(tocPath)
+			if err != nil {
+				return err
+			}
+			if tocFile.Remove(mp.Rel) {
+				removed = append(removed, fmt.Sprintf("%s from Moonlight.toc", mp.Rel))
+			}
+
+			// Kinds with an XML template list the XML file in the TOC, which
+			// loads the module. It would load a missing file once the module
+			// is gone, so it goes too.
+			xml, paired, err := mp.pairedXML()
+			if err != nil {
+				return err
+			}
+			if paired {
+				removed = append(removed, fmt.Sprintf("paired XML file %s", xml.Rel))
+				if tocFile.Remove(xml.Rel) {
+					removed = append(removed, fmt.Sprintf("%s from Moonlight.toc", xml.Rel))
+				}
+			}
+
+			cs := changeset.New(mp.Root)
+			cs.Write(bootPath, bootSrc, 0)
+			cs.Write(tocPath, tocFile.Bytes(), 0)
+			cs.Delete(mp.Abs)
+			if paired {
+				cs.Delete(xml.Abs)
+			}
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to delete module: %w", err)
+			}
+			if dryRun {
+				return nil
+			}
+
+			fmt.Printf("Module deleted: %s\n", mp.Abs)
+			for _, r := range removed {
+				fmt.Printf("  removed %s\n", r)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Delete the module even if other files still call its accessor")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+	return cmd
+}
@@ -0,0 +1,72 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeletePairedXML(t *testing.T) {
+	files := map[string]string{
+		"go.work":           "go 1.24\n",
+		"Moonlight.toc":     "boot/boot.lua\nframes/button.xml\n",
+		"boot/boot.lua":     "function Moonlight:Start()\nend\n",
+		"frames/button.lua": "ButtonMixin = {}\n",
+		"frames/other.lua":  "OtherMixin = {}\n",
+	}
+	tests := []struct {
+		name string
+		xml  string
+		// deleted is whether the module and its XML file are deleted.
+		deleted bool
+	}{
+		{"only the module", `<Ui><Script file="button.lua"/></Ui>`, true},
+		{"other files", `<Ui><Script file="button.lua"/><Script file="other.lua"/></Ui>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			files["frames/button.xml"] = tt.xml
+			for rel, content := range files {
+				path := filepath.Join(root, filepath.FromSlash(rel))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Chdir(root)
+
+			cmd := newDeleteCmd()
+			cmd.SetArgs([]string{"//frames/button.lua"})
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			err := cmd.Execute()
+			if tt.deleted && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.deleted && err == nil {
+				t.Fatal("module delete succeeded, want an error for the shared XML file")
+			}
+
+			for _, rel := range []string{"frames/button.lua", "frames/button.xml"} {
+				_, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel)))
+				if exists := !os.IsNotExist(err); exists == tt.deleted {
+					t.Errorf("%s exists = %v, want %v", rel, exists, !tt.deleted)
+				}
+			}
+			toc, err := os.ReadFile(filepath.Join(root, "Moonlight.toc"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := files["Moonlight.toc"]
+			if tt.deleted {
+				want = "boot/boot.lua\n"
+			}
+			if string(toc) != want {
+				t.Errorf("Moonlight.toc = %q, want %q", toc, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package module
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+)
+
+// TemplateDir is the repo URI of the project-local module templates. A
+// <kind>.lua.tmpl file overrides or adds a kind, and an optional
+// <kind>.xml.tmpl file pairs an XML file with it.
+const TemplateDir = "//tools/templates"
+
+// builtinSource is the Source of kinds that are compiled into the tool.
+const builtinSource = "built-in"
+
+// moduleKind describes the files that module create generates for a --kind.
+type moduleKind struct {
+	Name        string
+	Description string
+	// Lua is the template for the module's Lua file.
+	Lua string
+	// XML is the template for a paired XML file. When set, the XML file is
+	// listed in the TOC and loads the Lua file through a Script tag.
+	XML string
+	// Accessor is true when the kind registers a class and needs a boot accessor.
+	Accessor bool
+	// Source is where the templates come from, either built-in or the repo
+	// relative path of a project template.
+	Source string
+}
+
+var builtinKinds = map[string]moduleKind{
+	"pooled": {
+		Name:        "pooled",
+		Description: "A module package that hands out pooled instances via New()",
+		Lua:         ModuleTemplate,
+		Accessor:    true,
+		Source:      builtinSource,
+	},
+	"static": {
+		Name:        "static",
+		Description: "A static module package with no instances",
+		Lua:         StaticTemplate,
+		Accessor:    true,
+		Source:      builtinSource,
+	},
+	"drawable": {
+		Name:        "drawable",
+		Description: "A pooled module whose instances take part in the render pipeline",
+		Lua:         DrawableTemplate,
+		Accessor:    true,
+		Source:      builtinSource,
+	},
+	"theme": {
+		Name:        "theme",
+		Description: "A static module that registers a sonata theme in Boot()",
+		Lua:         ThemeTemplate,
+		Accessor:    true,
+		Source:      builtinSource,
+	},
+	"xml": {
+		Name:        "xml",
+		Description: "A virtual XML frame template paired with a Lua mixin",
+		Lua:         XMLMixinTemplate,
+		XML:         XMLTemplate,
+		Source:      builtinSource,
+	},
+}
+
+// reDescription matches a leading {{/* ... */}} template comment, which
+// project templates use to describe their kind.
+var reDescription = regexp.MustCompile(`^\{\{-?\s*/\*\s*(.*?)\s*\*/\s*-?\}\}`)
+
+// loadKinds returns the built-in kinds merged with the project templates in
+// TemplateDir under root. Every project template is parsed up front so that
+// a broken template fails before anything is generated.
+func loadKinds(root string) (map[string]moduleKind, error) {
+	kinds := make(map[string]moduleKind, len(builtinKinds))
+	for name, kind := range builtinKinds {
+		kinds[name] = kind
+	}
+
+	dirRel := strings.TrimPrefix(TemplateDir, "//")
+	dir := filepath.Join(root, filepath.FromSlash(dirRel))
+	luaFiles, err := filepath.Glob(filepath.Join(dir, "*.lua.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project templates: %w", err)
+	}
+	xmlFiles, err := filepath.Glob(filepath.Join(dir, "*.xml.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project templates: %w", err)
+	}
+
+	load := func(path string) (string, error) {
+		rel := dirRel + "/" + filepath.Base(path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read project template %s: %w", rel, err)
+		}
+		if _, err := template.New(rel).Parse(string(content)); err != nil {
+			return "", fmt.Errorf("failed to parse project template %s: %w", rel, err)
+		}
+		return string(content), nil
+	}
+
+	for _, path := range luaFiles {
+		name := strings.TrimSuffix(filepath.Base(path), ".lua.tmpl")
+		text, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		kind := kinds[name]
+		kind.Name = name
+		kind.Lua = text
+		kind.Accessor = strings.Contains(text, "NewClass(")
+		kind.Source = dirRel + "/" + filepath.Base(path)
+		if match := reDescription.FindStringSubmatch(text); match != nil {
+			kind.Description = match[1]
+		} else if kind.Description == "" {
+			kind.Description = "Project template"
+		}
+		kinds[name] = kind
+	}
+
+	for _, path := range xmlFiles {
+		name := strings.TrimSuffix(filepath.Base(path), ".xml.tmpl")
+		kind, ok := kinds[name]
+		if !ok {
+			return nil, fmt.Errorf("project template %s/%s has no matching %s.lua.tmpl or built-in kind", dirRel, filepath.Base(path), name)
+		}
+		text, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		kind.XML = text
+		if kind.Source == builtinSource {
+			kind.Source = dirRel + "/" + filepath.Base(path)
+		} else {
+			kind.Source += ", " + dirRel + "/" + filepath.Base(path)
+		}
+		kinds[name] = kind
+	}
+
+	return kinds, nil
+}
+
+// findKind returns the module kind with the given name.
+func findKind(kinds map[string]moduleKind, name string) (moduleKind, error) {
+	kind, ok := kinds[name]
+	if !ok {
+		return moduleKind{}, fmt.Errorf("unknown module kind %q, must be one of: %s", name, strings.Join(kindNames(kinds), ", "))
+	}
+	return kind, nil
+}
+
+// kindNames returns the names of all module kinds, sorted.
+func kindNames(kinds map[string]moduleKind) []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateData is the data every module template is executed with.
+type templateData struct {
+	moduleNames
+	// LuaFile is the file name of the generated Lua file, e.g. bag.lua.
+	LuaFile string
+	// RelPath is the repo relative path of the generated Lua file, e.g. data/bag.lua.
+	RelPath string
+	// Author is the git user.name, and email if set, of whoever runs the command.
+	Author string
+	// Date is the current date in YYYY-MM-DD form.
+	Date string
+}
+
+// newTemplateData builds the template data for a module at mp.
+func newTemplateData(mp modulePath, names moduleNames) templateData {
+	return templateData{
+		moduleNames: names,
+		LuaFile:     filepath.Base(mp.Abs),
+		RelPath:     mp.Rel,
+		Author:      gitAuthor(mp.Root),
+		Date:        time.Now().Format("2006-01-02"),
+	}
+}
+
+// gitAuthor returns the git user for the repo at root, or an empty string
+// if none is configured.
+func gitAuthor(root string) string {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return ""
+	}
+	cfg, err := repo.ConfigScoped(config.GlobalScope)
+	if err != nil {
+		return ""
+	}
+	if cfg.User.Email == "" {
+		return cfg.User.Name
+	}
+	return fmt.Sprintf("%s <%s>", cfg.User.Name, cfg.User.Email)
+}
+
+// renderTemplate parses and executes a single module template.
+func renderTemplate(name, text string, data templateData) ([]byte, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute %s template: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+var reBootFunc = regexp.MustCompile(`(?m)^function\s+[A-Za-z_][A-Za-z0-9_]*:Boot\s*\(`)
+
+// addBootStub appends an empty Boot() function to a generated module that
+// does not already define one, so that booting it from Moonlight:Start works.
+func addBootStub(src []byte, names moduleNames) []byte {
+	if reBootFunc.Match(src) {
+		return src
+	}
+	stub := fmt.Sprintf("\n--- Boot is called once from Moonlight:Start, after all modules are loaded.\nfunction %s:Boot()\nend\n", names.ModuleNameLower)
+	return append(src, stub...)
+}
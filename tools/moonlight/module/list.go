@@ -0,0 +1,233 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/boot"
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+// ErrInconsistent is returned by module list when it finds an issue.
+var ErrInconsistent = errors.New("modules are inconsistent")
+
+// listedModule is a single row of the module list report.
+type listedModule struct {
+	scan.Registration
+	Accessor string `json:"accessor"`
+	InTOC    bool   `json:"inToc"`
+}
+
+// issue is a single inconsistency found by the module list report.
+type issue struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// report is the consistency report across class registrations, boot
+// accessors and the TOC.
+type report struct {
+	Modules   []listedModule  `json:"modules"`
+	Accessors []boot.Accessor `json:"accessors"`
+	TOC       []string        `json:"toc"`
+	Issues    []issue         `json:"issues"`
+}
+
+// buildReport scans the repo at root and cross references every
+// registration, accessor and TOC entry.
+func buildReport(root string) (*report, error) {
+	regs, err := scan.Registrations(root)
+	if err != nil {
+		return nil, err
+	}
+
+	bootRel := strings.TrimPrefix(boot.Path, "//")
+	bootSrc, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(bootRel)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boot file: %w", err)
+	}
+	accessors := boot.Accessors(bootSrc)
+
+	tocFile, err := toc.Load(filepath.Join(root, "Moonlight.toc"))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &report{
+		Accessors: accessors,
+		TOC:       tocFile.Entries(),
+		Issues:    []issue{},
+	}
+
+	inTOC := make(map[string]bool)
+	for _, entry := range r.TOC {
+		inTOC[entry] = true
+	}
+
+	byClass := make(map[string]boot.Accessor)
+	for _, a := range accessors {
+		if a.Class != "" {
+			byClass[a.Class] = a
+		}
+	}
+
+	registered := make(map[string]scan.Registration)
+	for _, reg := range regs {
+		if prev, ok := registered[reg.Class]; ok {
+			r.Issues = append(r.Issues, issue{
+				Kind:    "duplicate-class",
+				Path:    reg.Path,
+				Line:    reg.Line,
+				Message: fmt.Sprintf("class %q is already registered at %s:%d", reg.Class, prev.Path, prev.Line),
+			})
+		} else {
+			registered[reg.Class] = reg
+		}
+
+		m := listedModule{Registration: reg, InTOC: inTOC[reg.Path]}
+		if a, ok := byClass[reg.Class]; ok {
+			m.Accessor = a.Name
+		} else {
+			r.Issues = append(r.Issues, issue{
+				Kind:    "missing-accessor",
+				Path:    reg.Path,
+				Line:    reg.Line,
+				Message: fmt.Sprintf("class %q has no Moonlight:Get accessor in %s", reg.Class, bootRel),
+			})
+		}
+		if !m.InTOC {
+			r.Issues = append(r.Issues, issue{
+				Kind:    "missing-toc",
+				Path:    reg.Path,
+				Line:    reg.Line,
+				Message: fmt.Sprintf("%s registers class %q but is not listed in Moonlight.toc", reg.Path, reg.Class),
+			})
+		}
+		r.Modules = append(r.Modules, m)
+	}
+
+	for _, a := range accessors {
+		switch {
+		case a.Class == "":
+			r.Issues = append(r.Issues, issue{
+				Kind:    "unknown-accessor",
+				Path:    bootRel,
+				Line:    a.Line,
+				Message: fmt.Sprintf("Moonlight:%s() does not return a class from self.classes", a.Name),
+			})
+		case registered[a.Class] == (scan.Registration{}):
+			r.Issues = append(r.Issues, issue{
+				Kind:    "unregistered-class",
+				Path:    bootRel,
+				Line:    a.Line,
+				Message: fmt.Sprintf("Moonlight:%s() returns class %q, which is never registered with NewClass", a.Name, a.Class),
+			})
+		case a.Return != a.Class:
+			r.Issues = append(r.Issues, issue{
+				Kind:    "return-mismatch",
+				Path:    bootRel,
+				Line:    a.Line,
+				Message: fmt.Sprintf("Moonlight:%s() is annotated to return %q but returns class %q", a.Name, a.Return, a.Class),
+			})
+		}
+	}
+
+	for _, entry := range r.TOC {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(entry))); os.IsNotExist(err) {
+			r.Issues = append(r.Issues, issue{
+				Kind:    "missing-file",
+				Path:    "Moonlight.toc",
+				Message: fmt.Sprintf("%s is listed in Moonlight.toc but does not exist", entry),
+			})
+		}
+	}
+
+	return r, nil
+}
+
+func newListCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List Moonlight modules and check them for consistency",
+		Long: `Lists every moonlight:NewClass registration, every Moonlight:GetX() accessor in boot/boot.lua
+and every Moonlight.toc entry, and reports any mismatch between them. The command exits
+with a non-zero status when inconsistencies are found.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+
+			r, err := buildReport(root)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(r); err != nil {
+					return fmt.Errorf("failed to encode report: %w", err)
+				}
+			} else {
+				printReport(r)
+			}
+
+			if len(r.Issues) > 0 {
+				// The issues are the expected outcome of the report, not a
+				// misuse of the command.
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return ErrInconsistent
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	return cmd
+}
+
+func printReport(r *report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLASS\tFILE\tACCESSOR\tTOC")
+	for _, m := range r.Modules {
+		accessor := m.Accessor
+		if accessor == "" {
+			accessor = "-"
+		}
+		listed := "yes"
+		if !m.InTOC {
+			listed = "no"
+		}
+		fmt.Fprintf(w, "%s\t%s:%d\t%s\t%s\n", m.Class, m.Path, m.Line, accessor, listed)
+	}
+	w.Flush()
+
+	if len(r.Issues) == 0 {
+		fmt.Printf("\n%d modules, %d accessors, %d toc entries, no inconsistencies found\n", len(r.Modules), len(r.Accessors), len(r.TOC))
+		return
+	}
+
+	fmt.Printf("\n%d inconsistencies found:\n", len(r.Issues))
+	for _, i := range r.Issues {
+		location := i.Path
+		if i.Line > 0 {
+			location = fmt.Sprintf("%s:%d", i.Path, i.Line)
+		}
+		fmt.Printf("  [%s] %s: %s\n", i.Kind, location, i.Message)
+	}
+}
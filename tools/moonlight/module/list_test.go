@@ -0,0 +1,53 @@
+package module
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListCmd(t *testing.T) {
+	files := map[string]string{
+		"go.work":       "go 1.24\n",
+		"Moonlight.toc": "boot/boot.lua\nbag/bag.lua\n",
+		"boot/boot.lua": `---@return bag
+function Moonlight:GetBag()
+  return self.classes.bag
+end
+`,
+		"bag/bag.lua": `local bag = moonlight:NewClass("bag")`,
+	}
+	tests := []struct {
+		name string
+		// extra is written on top of files.
+		extra map[string]string
+		want  error
+	}{
+		{"consistent", nil, nil},
+		{"unlisted module", map[string]string{"item/item.lua": `moonlight:NewClass("item")`}, ErrInconsistent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, set := range []map[string]string{files, tt.extra} {
+				for rel, content := range set {
+					path := filepath.Join(root, filepath.FromSlash(rel))
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			t.Chdir(root)
+
+			cmd := newListCmd()
+			cmd.SetArgs([]string{"--json"})
+			if err := cmd.Execute(); !errors.Is(err, tt.want) {
+				t.Errorf("module list = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -4,16 +4,24 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
-	"text/template"
-	"unicode"
 
+	"github.com/Cidan/Moonlight/tools/moonlight/boot"
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
 	"github.com/Cidan/Moonlight/tools/moonlight/util"
 	"github.com/spf13/cobra"
 )
 
 // NewModuleCmd creates and returns the module command with its subcommands.
 func NewModuleCmd() *cobra.Command {
+	var tocPos toc.Position
+	var kindName string
+	var bootModule bool
+	var bootAfter string
+	var dryRun bool
+
 	// moduleCmd represents the module command
 	var moduleCmd = &cobra.Command{
 		Use:   "module",
@@ -25,115 +33,153 @@ func NewModuleCmd() *cobra.Command {
 	var createCmd = &cobra.Command{
 		Use:   "create [path]",
 		Short: "Create a new Moonlight module",
-		Long:  `Creates a new, empty Moonlight module at the specified repo URI path (e.g. //folder/MyModule.lua).`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Creates a new, empty Moonlight module at the specified repo URI path (e.g. //folder/MyModule.lua).
+The module is added to Moonlight.toc next to the other files in its directory, or at the
+position given by --after or --before.
+
+The --kind flag selects what is generated:
+  pooled    a module package that hands out pooled instances via New() (default)
+  static    a static module package with no instances
+  drawable  a pooled module with the GetRenderPlan/PreRender/Render skeleton
+  theme     a static module whose Boot() registers a sonata theme
+  xml       a virtual XML frame template and its Lua mixin
+
+With --boot the module is bound in Moonlight:Start and its Boot() function is called there,
+after the module given by --boot-after or after every other module by default.
+
+Project templates in //tools/templates override or add kinds, see 'moonlight module templates'.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			uriPath := args[0]
-			if !strings.HasSuffix(uriPath, ".lua") {
-				return fmt.Errorf("path must end with a .lua extension")
-			}
-
-			filePath, err := util.GetRepoPath(uriPath)
+			mp, err := resolveModulePath(args[0])
 			if err != nil {
-				return fmt.Errorf("invalid repo path: %w", err)
-			}
-
-			if _, err := os.Stat(filePath); !os.IsNotExist(err) {
-				return fmt.Errorf("file already exists at: %s", filePath)
-			}
-
-			baseName := strings.TrimSuffix(filepath.Base(filePath), ".lua")
-			if baseName == "" {
-				return fmt.Errorf("could not determine module name from path")
+				return err
 			}
+			filePath := mp.Abs
 
-			// Create ModuleName (e.g. newBag -> NewBag)
-			var moduleName string
-			if r := rune(baseName[0]); unicode.IsLower(r) {
-				moduleName = string(unicode.ToUpper(r)) + baseName[1:]
-			} else {
-				moduleName = baseName
-			}
-
-			// Create moduleNameLower (e.g. NewBag -> newBag)
-			var moduleNameLower string
-			if r := rune(moduleName[0]); unicode.IsUpper(r) {
-				moduleNameLower = string(unicode.ToLower(r)) + moduleName[1:]
-			} else {
-				moduleNameLower = moduleName
-			}
-
-			tmpl, err := template.New("module").Parse(ModuleTemplate)
+			kinds, err := loadKinds(mp.Root)
 			if err != nil {
-				return fmt.Errorf("failed to parse module template: %w", err)
+				return err
 			}
-
-			// Ensure the directory exists
-			if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
-
-			file, err := os.Create(filePath)
+			kind, err := findKind(kinds, kindName)
 			if err != nil {
-				return fmt.Errorf("failed to create module file: %w", err)
+				return err
 			}
-			defer file.Close()
 
-			data := struct {
-				ModuleName      string
-				ModuleNameLower string
-			}{
-				ModuleName:      moduleName,
-				ModuleNameLower: moduleNameLower,
-			}
-
-			err = tmpl.Execute(file, data)
+			names, err := namesFromBase(mp.baseName())
 			if err != nil {
-				return fmt.Errorf("failed to execute template: %w", err)
-			}
-
-			bootPath, err := util.GetRepoPath("//boot/boot.lua")
+				return err
+			}
+			if err := validateNames(mp, names, kind.Accessor, ""); err != nil {
+				return err
+			}
+			data := newTemplateData(mp, names)
+
+			// Each generated file, keyed by absolute path. Kinds with an XML
+			// file list the XML file in the TOC, which then loads the Lua file.
+			files := make(map[string][]byte)
+			tocRel := mp.Rel
+			if files[filePath], err = renderTemplate(kind.Source, kind.Lua, data); err != nil {
+				return err
+			}
+			if kind.XML != "" {
+				xmlPath := strings.TrimSuffix(filePath, ".lua") + ".xml"
+				if files[xmlPath], err = renderTemplate(kind.Source, kind.XML, data); err != nil {
+					return err
+				}
+				tocRel = strings.TrimSuffix(mp.Rel, ".lua") + ".xml"
+			}
+
+			for path := range files {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					return fmt.Errorf("file already exists at: %s", path)
+				}
+			}
+
+			if bootModule && !kind.Accessor {
+				return fmt.Errorf("modules of kind %q do not register a class and can not be booted", kind.Name)
+			}
+			if bootAfter != "" && !bootModule {
+				return fmt.Errorf("--boot-after requires --boot")
+			}
+
+			// The accessor is added to the generated accessor block in boot.lua,
+			// and with --boot the module is also bound and booted inside
+			// Moonlight:Start.
+			var bootPath string
+			var bootSrc []byte
+			if kind.Accessor {
+				if bootPath, err = util.GetRepoPath(boot.Path); err != nil {
+					return fmt.Errorf("failed to get boot path: %w", err)
+				}
+				if bootSrc, err = os.ReadFile(bootPath); err != nil {
+					return fmt.Errorf("failed to read boot file: %w", err)
+				}
+				if bootSrc, err = boot.InsertAccessor(bootSrc, names.ModuleNameLower); err != nil {
+					return err
+				}
+
+				if bootModule {
+					var after string
+					if bootAfter != "" {
+						if after, err = boot.ResolveAccessor(bootSrc, bootAfter); err != nil {
+							return err
+						}
+					}
+					if bootSrc, err = boot.AddBoot(bootSrc, names.Accessor(), names.ModuleNameLower, after); err != nil {
+						return fmt.Errorf("failed to add module to Moonlight:Start: %w", err)
+					}
+					files[filePath] = addBootStub(files[filePath], names)
+				}
+			}
+
+			// Stage the TOC edit first so that a bad --after/--before fails
+			// before anything is written.
+			tocPath := filepath.Join(mp.Root, "Moonlight.toc")
+			tocFile, err := toc.Load(tocPath)
 			if err != nil {
-				return fmt.Errorf("failed to get boot path: %w", err)
+				return err
 			}
-
-			bootFile, err := os.OpenFile(bootPath, os.O_APPEND|os.O_WRONLY, 0644)
-			if err != nil {
-				return fmt.Errorf("failed to open boot file: %w", err)
+			if err := tocFile.Insert(tocRel, tocPos); err != nil {
+				return fmt.Errorf("failed to add module to toc: %w", err)
 			}
-			defer bootFile.Close()
 
-			bootTmpl, err := template.New("boot").Parse(BootTemplate)
-			if err != nil {
-				return fmt.Errorf("failed to parse boot template: %w", err)
+			cs := changeset.New(mp.Root)
+			paths := make([]string, 0, len(files))
+			for path := range files {
+				paths = append(paths, path)
 			}
-
-			// Add a newline before appending the new content
-			if _, err := bootFile.WriteString("\n"); err != nil {
-				return fmt.Errorf("failed to write newline to boot file: %w", err)
+			sort.Strings(paths)
+			for _, path := range paths {
+				cs.Write(path, files[path], 0644)
 			}
-
-			err = bootTmpl.Execute(bootFile, data)
-			if err != nil {
-				return fmt.Errorf("failed to execute boot template: %w", err)
+			if bootSrc != nil {
+				cs.Write(bootPath, bootSrc, 0)
+			}
+			cs.Write(tocPath, tocFile.Bytes(), 0)
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to create module: %w", err)
+			}
+			if dryRun {
+				return nil
 			}
 
-			fmt.Printf("Module '%s' created at %s\n", moduleName, filePath)
+			fmt.Printf("Module '%s' (%s) created at %s\n", names.ModuleName, kind.Name, filePath)
 			return nil
 		},
 	}
 
-	// deleteCmd represents the delete command
-	var deleteCmd = &cobra.Command{
-		Use:   "delete",
-		Short: "Delete a Moonlight module",
-		Long:  `Deletes an existing Moonlight module by name.`,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("module delete called (noop)")
-		},
-	}
+	createCmd.Flags().StringVar(&tocPos.After, "after", "", "Add the module to Moonlight.toc after this entry (e.g. //data/item.lua)")
+	createCmd.Flags().StringVar(&tocPos.Before, "before", "", "Add the module to Moonlight.toc before this entry (e.g. //data/item.lua)")
+	createCmd.MarkFlagsMutuallyExclusive("after", "before")
+	createCmd.Flags().BoolVar(&bootModule, "boot", false, "Call the module's Boot() function from Moonlight:Start")
+	createCmd.Flags().StringVar(&bootAfter, "boot-after", "", "Boot the module right after this module (e.g. save), instead of last")
+	createCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+	createCmd.Flags().StringVar(&kindName, "kind", "pooled", "The kind of module to create, see 'moonlight module templates'")
 
 	moduleCmd.AddCommand(createCmd)
-	moduleCmd.AddCommand(deleteCmd)
+	moduleCmd.AddCommand(newDeleteCmd())
+	moduleCmd.AddCommand(newRenameCmd())
+	moduleCmd.AddCommand(newListCmd())
+	moduleCmd.AddCommand(newTemplatesCmd())
 	return moduleCmd
 }
@@ -0,0 +1,160 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+)
+
+// moduleNames holds the casing variants of a module name that are used by
+// the templates, the boot accessors and the class registration.
+type moduleNames struct {
+	// ModuleName is the instance name, e.g. NewBag.
+	ModuleName string
+	// ModuleNameLower is the package name, e.g. newBag.
+	ModuleNameLower string
+}
+
+// namesFromBase derives the module names from a file base name or a class
+// name, e.g. newBag or NewBag.
+func namesFromBase(baseName string) (moduleNames, error) {
+	if baseName == "" {
+		return moduleNames{}, fmt.Errorf("could not determine module name from path")
+	}
+
+	// Create ModuleName (e.g. newBag -> NewBag)
+	var moduleName string
+	if r := rune(baseName[0]); unicode.IsLower(r) {
+		moduleName = string(unicode.ToUpper(r)) + baseName[1:]
+	} else {
+		moduleName = baseName
+	}
+
+	// Create moduleNameLower (e.g. NewBag -> newBag)
+	var moduleNameLower string
+	if r := rune(moduleName[0]); unicode.IsUpper(r) {
+		moduleNameLower = string(unicode.ToLower(r)) + moduleName[1:]
+	} else {
+		moduleNameLower = moduleName
+	}
+
+	return moduleNames{
+		ModuleName:      moduleName,
+		ModuleNameLower: moduleNameLower,
+	}, nil
+}
+
+// Accessor returns the name of the Moonlight accessor for the module, e.g. GetNewBag.
+func (n moduleNames) Accessor() string {
+	return "Get" + n.ModuleName
+}
+
+// modulePath is a module file location resolved from a repo URI.
+type modulePath struct {
+	// Root is the absolute path to the repo root.
+	Root string
+	// Abs is the absolute path to the module file.
+	Abs string
+	// Rel is the repo relative, forward slash path to the module file.
+	Rel string
+}
+
+// resolveModulePath resolves a //path/name.lua URI into a module path.
+func resolveModulePath(uriPath string) (modulePath, error) {
+	if !strings.HasSuffix(uriPath, ".lua") {
+		return modulePath{}, fmt.Errorf("path must end with a .lua extension")
+	}
+
+	root, err := util.FindRepoRoot()
+	if err != nil {
+		return modulePath{}, fmt.Errorf("failed to find repo root: %w", err)
+	}
+
+	filePath, err := util.GetRepoPath(uriPath)
+	if err != nil {
+		return modulePath{}, fmt.Errorf("invalid repo path: %w", err)
+	}
+	filePath, err = filepath.Abs(filePath)
+	if err != nil {
+		return modulePath{}, fmt.Errorf("invalid repo path: %w", err)
+	}
+
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return modulePath{}, fmt.Errorf("path %s is outside of the repo", uriPath)
+	}
+
+	return modulePath{
+		Root: root,
+		Abs:  filePath,
+		Rel:  filepath.ToSlash(rel),
+	}, nil
+}
+
+// baseName returns the file name of the module without the .lua extension.
+func (p modulePath) baseName() string {
+	return strings.TrimSuffix(filepath.Base(p.Abs), ".lua")
+}
+
+// xmlPath returns the path of the XML file next to the module, with the
+// same name.
+func (p modulePath) xmlPath() modulePath {
+	return modulePath{
+		Root: p.Root,
+		Abs:  strings.TrimSuffix(p.Abs, ".lua") + ".xml",
+		Rel:  strings.TrimSuffix(p.Rel, ".lua") + ".xml",
+	}
+}
+
+// pairedXML returns the XML file next to the module that loads it, as the
+// kinds with an XML template create, and false if there is none. An XML
+// file that loads other files as well is not the module's to delete or
+// move, so it is an error.
+func (p modulePath) pairedXML() (modulePath, bool, error) {
+	xml := p.xmlPath()
+	refs, err := toc.References(p.Root, xml.Rel)
+	if os.IsNotExist(err) {
+		return modulePath{}, false, nil
+	}
+	if err != nil {
+		return modulePath{}, false, fmt.Errorf("failed to read %s: %w", xml.Rel, err)
+	}
+	if !slices.Contains(refs, p.Rel) {
+		return modulePath{}, false, nil
+	}
+	var others []string
+	for _, ref := range refs {
+		if ref != p.Rel && !slices.Contains(others, ref) {
+			others = append(others, ref)
+		}
+	}
+	if len(others) > 0 {
+		return modulePath{}, false, fmt.Errorf("%s loads %s as well as the module, update it by hand", xml.Rel, strings.Join(others, ", "))
+	}
+	return xml, true, nil
+}
+
+var reXMLFileAttr = regexp.MustCompile(`(<(?:Script|Include)\b[^>]*?\bfile\s*=\s*)("[^"]*"|'[^']*')`)
+
+// retargetXML points the Script and Include elements of the XML file at
+// xmlRel that load the module at from to the module at to, which sits next
+// to the XML file once it is moved.
+func retargetXML(src []byte, xmlRel, from, to string) []byte {
+	return reXMLFileAttr.ReplaceAllFunc(src, func(m []byte) []byte {
+		match := reXMLFileAttr.FindSubmatch(m)
+		quoted := string(match[2])
+		ref := path.Join(path.Dir(xmlRel), toc.NormalizePath(quoted[1:len(quoted)-1]))
+		if ref != from {
+			return m
+		}
+		return []byte(string(match[1]) + quoted[:1] + path.Base(to) + quoted[:1])
+	})
+}
@@ -0,0 +1,332 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/boot"
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+func newRenameCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "rename [old path] [new path]",
+		Short: "Rename a Moonlight module",
+		Long: `Renames a Moonlight module from one repo URI path to another (e.g. //data/bag.lua //data/pack.lua).
+The module file is moved and its class registration, class annotations, boot accessor,
+Moonlight.toc entry and every moonlight:GetX() caller are rewritten to the new name.
+The XML file next to the module that loads it, if any, is moved along with it, and the
+rename is refused when that XML file loads other files as well.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := resolveModulePath(args[0])
+			if err != nil {
+				return err
+			}
+			to, err := resolveModulePath(args[1])
+			if err != nil {
+				return err
+			}
+
+			content, err := os.ReadFile(from.Abs)
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no module exists at: %s", from.Abs)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read module file: %w", err)
+			}
+			if _, err := os.Stat(to.Abs); !os.IsNotExist(err) {
+				return fmt.Errorf("file already exists at: %s", to.Abs)
+			}
+			fromXML, paired, err := from.pairedXML()
+			if err != nil {
+				return err
+			}
+			toXML := to.xmlPath()
+			if paired {
+				if _, err := os.Stat(toXML.Abs); !os.IsNotExist(err) {
+					return fmt.Errorf("file already exists at: %s", toXML.Abs)
+				}
+			}
+
+			// The current names come from the registered class, the new names
+			// from the new file name, exactly as create would derive them.
+			fromBase := from.baseName()
+			switch classes := scan.Classes(content); len(classes) {
+			case 0:
+			case 1:
+				fromBase = classes[0]
+			default:
+				return fmt.Errorf("module registers more than one class (%s), rename it by hand", strings.Join(classes, ", "))
+			}
+			fromNames, err := namesFromBase(fromBase)
+			if err != nil {
+				return err
+			}
+			toNames, err := namesFromBase(to.baseName())
+			if err != nil {
+				return err
+			}
+			if err := validateNames(to, toNames, true, fromNames.ModuleNameLower); err != nil {
+				return err
+			}
+
+			touched := make(map[string][]string)
+			cs := changeset.New(from.Root)
+
+			moduleSrc, err := renameModuleSource(string(content), fromNames, toNames)
+			if err != nil {
+				return fmt.Errorf("failed to parse module file: %w", err)
+			}
+			cs.Write(to.Abs, []byte(moduleSrc), 0)
+			cs.Delete(from.Abs)
+			touched[to.Rel] = append(touched[to.Rel], fmt.Sprintf("moved from %s", from.Rel))
+			if moduleSrc != string(content) {
+				touched[to.Rel] = append(touched[to.Rel], fmt.Sprintf("renamed %s/%s to %s/%s", fromNames.ModuleNameLower, fromNames.ModuleName, toNames.ModuleNameLower, toNames.ModuleName))
+			}
+
+			// The XML file that loads the module moves along with it.
+			if paired {
+				xmlSrc, err := os.ReadFile(fromXML.Abs)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", fromXML.Rel, err)
+				}
+				cs.Write(toXML.Abs, retargetXML(xmlSrc, fromXML.Rel, from.Rel, to.Rel), 0)
+				cs.Delete(fromXML.Abs)
+				touched[toXML.Rel] = append(touched[toXML.Rel], fmt.Sprintf("moved from %s", fromXML.Rel))
+			}
+
+			bootRel := strings.TrimPrefix(boot.Path, "//")
+			bootPath, err := util.GetRepoPath(boot.Path)
+			if err != nil {
+				return fmt.Errorf("failed to get boot path: %w", err)
+			}
+			bootSrc, err := os.ReadFile(bootPath)
+			if err != nil {
+				return fmt.Errorf("failed to read boot file: %w", err)
+			}
+			if bootSrc, ok := boot.RenameAccessor(bootSrc, fromNames.Accessor(), toNames.Accessor(), fromNames.ModuleNameLower, toNames.ModuleNameLower); ok {
+				cs.Write(bootPath, bootSrc, 0)
+				touched[bootRel] = append(touched[bootRel], fmt.Sprintf("renamed accessor Moonlight:%s() to Moonlight:%s()", fromNames.Accessor(), toNames.Accessor()))
+			}
+
+			tocPath := filepath.Join(from.Root, "Moonlight.toc")
+			tocFile, err := toc.Load(tocPath)
+			if err != nil {
+				return err
+			}
+			renames := [][2]string{{from.Rel, to.Rel}}
+			if paired {
+				renames = append(renames, [2]string{fromXML.Rel, toXML.Rel})
+			}
+			for _, r := range renames {
+				if tocFile.Rename(r[0], r[1]) {
+					cs.Write(tocPath, tocFile.Bytes(), 0)
+					touched["Moonlight.toc"] = append(touched["Moonlight.toc"], fmt.Sprintf("renamed entry %s to %s", r[0], r[1]))
+				}
+			}
+
+			files, err := scan.LuaFiles(from.Root)
+			if err != nil {
+				return err
+			}
+			for _, rel := range files {
+				if rel == from.Rel {
+					continue
+				}
+				path := filepath.Join(from.Root, filepath.FromSlash(rel))
+				src, err := cs.Read(path)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", rel, err)
+				}
+				updated, changes, err := renameReferences(string(src), fromNames, toNames)
+				if err != nil {
+					return fmt.Errorf("failed to parse %s: %w", rel, err)
+				}
+				if changes == 0 {
+					continue
+				}
+				cs.Write(path, []byte(updated), 0)
+				touched[rel] = append(touched[rel], fmt.Sprintf("updated %d reference(s)", changes))
+			}
+
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to rename module: %w", err)
+			}
+			if dryRun {
+				return nil
+			}
+
+			fmt.Printf("Module '%s' renamed to '%s'\n", fromNames.ModuleName, toNames.ModuleName)
+			paths := make([]string, 0, len(touched))
+			for rel := range touched {
+				paths = append(paths, rel)
+			}
+			sort.Strings(paths)
+			for _, rel := range paths {
+				fmt.Printf("  %s: %s\n", rel, strings.Join(touched[rel], ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+	return cmd
+}
+
+// renameModuleSource renames the package and instance identifiers, the
+// class registration, the pool keys and the annotations inside the module
+// file itself. Plain comments and unrelated strings are left alone.
+func renameModuleSource(src string, from, to moduleNames) (string, error) {
+	idents := map[string]string{
+		from.ModuleNameLower:                   to.ModuleNameLower,
+		from.ModuleName:                        to.ModuleName,
+		from.ModuleNameLower + "Constructor":   to.ModuleNameLower + "Constructor",
+		from.ModuleNameLower + "Deconstructor": to.ModuleNameLower + "Deconstructor",
+	}
+	reWords := wordsRegexp(idents)
+
+	return scan.Rewrite(src, scan.Rewriter{
+		Ident: func(name string, member bool) string {
+			if member {
+				if name == from.Accessor() {
+					return to.Accessor()
+				}
+				return name
+			}
+			if r, ok := idents[name]; ok {
+				return r
+			}
+			return name
+		},
+		String: func(lit string) string {
+			if len(lit) < 2 || (lit[0] != '"' && lit[0] != '\'') {
+				return lit
+			}
+			if r, ok := idents[lit[1:len(lit)-1]]; ok {
+				return lit[:1] + r + lit[len(lit)-1:]
+			}
+			return lit
+		},
+		Comment: func(text string) string {
+			if !strings.HasPrefix(text, "---@") {
+				return text
+			}
+			return reWords.ReplaceAllStringFunc(text, func(w string) string {
+				return idents[w]
+			})
+		},
+	})
+}
+
+// renameReferences rewrites accessor calls and annotation type references to
+// the module in any other Lua file, and returns the number of changes made.
+func renameReferences(src string, from, to moduleNames) (string, int, error) {
+	changes := 0
+	reCall := regexp.MustCompile(`\b(moonlight|self):` + regexp.QuoteMeta(from.Accessor()) + `(\s*\()`)
+	src = reCall.ReplaceAllStringFunc(src, func(m string) string {
+		changes++
+		return reCall.ReplaceAllString(m, "$1:"+to.Accessor()+"$2")
+	})
+
+	types := map[string]string{
+		from.ModuleNameLower: to.ModuleNameLower,
+		from.ModuleName:      to.ModuleName,
+	}
+	src, err := scan.Rewrite(src, scan.Rewriter{
+		Comment: func(text string) string {
+			updated := renameAnnotationTypes(text, types)
+			if updated != text {
+				changes++
+			}
+			return updated
+		},
+	})
+	return src, changes, err
+}
+
+var reAnnotation = regexp.MustCompile(`^(---@(\w+)\s+)(.*)$`)
+
+// renameAnnotationTypes renames types in the type position of a single
+// annotation comment. Parameter and field names and trailing descriptions
+// are never touched, since they often reuse the module name as a word.
+func renameAnnotationTypes(text string, types map[string]string) string {
+	match := reAnnotation.FindStringSubmatch(text)
+	if match == nil {
+		return text
+	}
+	prefix, tag, rest := match[1], match[2], match[3]
+
+	fields := strings.Fields(rest)
+	var typeIndex int
+	switch tag {
+	case "param", "field":
+		typeIndex = 1
+	case "type", "return", "class", "cast", "as", "alias":
+		typeIndex = 0
+	default:
+		return text
+	}
+	if len(fields) <= typeIndex {
+		return text
+	}
+
+	reWords := wordsRegexp(types)
+	replace := func(s string) string {
+		return reWords.ReplaceAllStringFunc(s, func(w string) string {
+			return types[w]
+		})
+	}
+
+	// Rebuild the line so that only the type token, and for classes the
+	// parent list, are rewritten while spacing is preserved.
+	idx := tokenOffset(rest, typeIndex)
+	typeTok := fields[typeIndex]
+	after := rest[idx+len(typeTok):]
+	if tag == "class" {
+		if parents, desc, ok := strings.Cut(after, ":"); ok && !strings.Contains(typeTok, ":") {
+			after = replace(parents) + ":" + desc
+		}
+		if strings.Contains(typeTok, ":") {
+			after = replace(after)
+		}
+	}
+	return prefix + rest[:idx] + replace(typeTok) + after
+}
+
+// tokenOffset returns the byte offset of the n-th whitespace separated token.
+func tokenOffset(s string, n int) int {
+	i := 0
+	for t := 0; ; t++ {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if t == n {
+			return i
+		}
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+	}
+}
+
+// wordsRegexp builds a regexp that matches any of the map keys as whole words.
+func wordsRegexp(words map[string]string) *regexp.Regexp {
+	quoted := make([]string, 0, len(words))
+	for w := range words {
+		quoted = append(quoted, regexp.QuoteMeta(w))
+	}
+	// Longer words first so that prefixes never shadow a longer match.
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
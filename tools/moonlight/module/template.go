@@ -1,5 +1,7 @@
 package module
 
+// ModuleTemplate is the pooled instance module, where the package space hands
+// out recyclable instances via New().
 const ModuleTemplate = `local moonlight = GetMoonlight()
 
 --- Describe in a comment what this module does. Note the lower case starting letter -- this denotes a module package accessor.
@@ -42,7 +44,144 @@ function {{.ModuleName}}:Release()
 end
 `
 
-const BootTemplate = `---@return {{.ModuleNameLower}}
-function Moonlight:Get{{.ModuleName}}()
-  return self.classes.{{.ModuleNameLower}}
-end`
+// StaticTemplate is a static package with no instances.
+const StaticTemplate = `local moonlight = GetMoonlight()
+
+--- Describe in a comment what this module does. Note the lower case starting letter -- this denotes a module package accessor.
+--- Static modules have no instances; all functionality lives directly on the package.
+---@class {{.ModuleNameLower}}
+local {{.ModuleNameLower}} = moonlight:NewClass("{{.ModuleNameLower}}")
+
+--- Describe what this function does.
+function {{.ModuleNameLower}}:Example()
+end
+`
+
+// DrawableTemplate is a pooled instance module that takes part in the
+// render pipeline via GetRenderPlan, PreRender and Render.
+const DrawableTemplate = `local moonlight = GetMoonlight()
+
+--- Describe in a comment what this module does. Note the lower case starting letter -- this denotes a module package accessor.
+---@class {{.ModuleNameLower}}
+---@field pool Pool
+local {{.ModuleNameLower}} = moonlight:NewClass("{{.ModuleNameLower}}")
+
+--- This is the instance of a module, and where the module
+--- functionality actually is. Note the upper case starting letter -- this denotes a module instance.
+--- Make sure to define all instance variables here. Private variables start with a lower case, public variables start with an upper case. 
+---@class {{.ModuleName}}: Drawable
+---@field frame_Container Frame
+local {{.ModuleName}} = {}
+
+---@return {{.ModuleName}}
+local {{.ModuleNameLower}}Constructor = function()
+  local drawable = moonlight:GetDrawable()
+  ---@type {{.ModuleName}}
+  local instance = drawable:Create({{.ModuleName}})
+
+  instance.frame_Container = CreateFrame("Frame")
+
+  return instance
+end
+
+---@param w {{.ModuleName}}
+local {{.ModuleNameLower}}Deconstructor = function(w)
+end
+
+--- This creates a new instance of a module, and optionally, initializes the module.
+---@return {{.ModuleName}}
+function {{.ModuleNameLower}}:New()
+  if self.pool == nil then
+    self.pool = moonlight:GetPool():New({{.ModuleNameLower}}Constructor, {{.ModuleNameLower}}Deconstructor)
+  end
+
+  return self.pool:TakeOne("{{.ModuleName}}")
+end
+
+function {{.ModuleName}}:Release()
+  {{.ModuleNameLower}}.pool:GiveBack("{{.ModuleName}}", self)
+end
+
+-- This is the render plan for this drawable. Add a RENDER_DEP step with a
+-- target for every child that must render before this drawable renders itself.
+function {{.ModuleName}}:GetRenderPlan()
+  ---@type RenderPlan
+  local plan = {
+    Plan = {
+      [1] = {
+        step = "RENDER_PRE"
+      },
+      [2] = {
+        step = "RENDER_SELF"
+      }
+    }
+  }
+  return plan
+end
+
+-- This is the pre-render function. The result is passed down to all children.
+---@param parentResult? RenderResult
+---@param options RenderOptions
+---@return RenderResult
+function {{.ModuleName}}:PreRender(parentResult, options)
+  ---@type RenderResult
+  local result = {
+    Width = self.frame_Container:GetWidth(),
+    Height = self.frame_Container:GetHeight()
+  }
+  return result
+end
+
+-- This is the self render function. parentResult is what the parent passed
+-- down, and results holds the results of all children.
+---@param parentResult? RenderResult
+---@param options RenderOptions
+---@param results RenderResults
+---@return RenderResult
+function {{.ModuleName}}:Render(parentResult, options, results)
+  ---@type RenderResult
+  local result = {
+    Width = self.frame_Container:GetWidth(),
+    Height = self.frame_Container:GetHeight()
+  }
+  return result
+end
+`
+
+// ThemeTemplate is a static package that registers a sonata theme on boot.
+const ThemeTemplate = `local moonlight = GetMoonlight()
+
+--- Describe in a comment what this module does. Note the lower case starting letter -- this denotes a module package accessor.
+---@class {{.ModuleNameLower}}
+local {{.ModuleNameLower}} = moonlight:NewClass("{{.ModuleNameLower}}")
+
+function {{.ModuleNameLower}}:Boot()
+  local engine = moonlight:GetSonataEngine()
+
+  engine:RegisterTheme({
+    Name = "{{.ModuleNameLower}}",
+  })
+end
+`
+
+// XMLTemplate is a virtual frame template that is paired with XMLMixinTemplate.
+const XMLTemplate = `<Ui xmlns="http://www.blizzard.com/wow/ui/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.blizzard.com/wow/ui/ ..\..\..\..\..\WoW\Data\Interface\AddOns\Blizzard_SharedXML\UI.xsd">
+  <Script file="{{.LuaFile}}"/>
+  <Frame name="Moonlight{{.ModuleName}}Template" mixin="Moonlight{{.ModuleName}}Mixin" virtual="true">
+    <Size x="256" y="256"/>
+    <Scripts>
+      <OnLoad method="OnLoad"/>
+    </Scripts>
+  </Frame>
+</Ui>
+`
+
+// XMLMixinTemplate is the Lua mixin for a frame created from XMLTemplate.
+const XMLMixinTemplate = `--- Describe in a comment what this template does. Frames created with
+--- the Moonlight{{.ModuleName}}Template XML template inherit this mixin.
+---@class Moonlight{{.ModuleName}}Mixin: Frame
+Moonlight{{.ModuleName}}Mixin = {}
+
+function Moonlight{{.ModuleName}}Mixin:OnLoad()
+end
+`
@@ -0,0 +1,51 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+func newTemplatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "templates",
+		Short: "List the available module templates",
+		Long: `Lists every module kind that can be passed to 'module create --kind', along with where its
+templates come from. Project templates live in //tools/templates as <kind>.lua.tmpl, with an
+optional <kind>.xml.tmpl, and override the built-in kind of the same name.
+
+Templates are Go text/template files executed with these fields:
+  .ModuleName       the instance name, e.g. NewBag
+  .ModuleNameLower  the package name, e.g. newBag
+  .LuaFile          the generated Lua file name, e.g. newBag.lua
+  .RelPath          the repo relative path, e.g. bags/newBag.lua
+  .Author           the git user.name and user.email
+  .Date             the current date, e.g. 2025-01-31
+
+A leading {{/* ... */}} comment in a project template is shown as its description.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+
+			kinds, err := loadKinds(root)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tSOURCE\tDESCRIPTION")
+			for _, name := range kindNames(kinds) {
+				kind := kinds[name]
+				fmt.Fprintf(w, "%s\t%s\t%s\n", kind.Name, kind.Source, kind.Description)
+			}
+			return w.Flush()
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,157 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/boot"
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+)
+
+// luaReserved are the reserved words of Lua 5.1, which can not be used as
+// identifiers.
+var luaReserved = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true,
+	"end": true, "false": true, "for": true, "function": true, "if": true,
+	"in": true, "local": true, "nil": true, "not": true, "or": true,
+	"repeat": true, "return": true, "then": true, "true": true,
+	"until": true, "while": true,
+}
+
+var reLuaIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validIdentifier returns an error if name is not a valid Lua 5.1 identifier.
+func validIdentifier(name string) error {
+	if !reLuaIdent.MatchString(name) {
+		return fmt.Errorf("%q is not a valid Lua identifier", name)
+	}
+	if luaReserved[name] {
+		return fmt.Errorf("%q is a reserved word in Lua", name)
+	}
+	return nil
+}
+
+// validateNames checks that the names derived for the module at mp produce
+// valid Lua, and when checkClasses is set, that they do not collide with any
+// class registration or accessor. The class named ignore, if any, is the
+// module's own class and never counts as a collision.
+func validateNames(mp modulePath, names moduleNames, checkClasses bool, ignore string) error {
+	for _, name := range []string{names.ModuleNameLower, names.ModuleName} {
+		if err := validIdentifier(name); err != nil {
+			return invalidName(mp, err, suggestNames(mp, nil))
+		}
+	}
+	if !checkClasses {
+		return nil
+	}
+
+	taken, err := takenNames(mp.Root)
+	if err != nil {
+		return err
+	}
+	if where, ok := taken[names.ModuleNameLower]; ok && names.ModuleNameLower != ignore {
+		err := fmt.Errorf("class %q is already registered at %s", names.ModuleNameLower, where)
+		return invalidName(mp, err, suggestNames(mp, taken))
+	}
+	if where, ok := taken[names.Accessor()]; ok && names.ModuleNameLower != ignore {
+		err := fmt.Errorf("accessor Moonlight:%s() already exists at %s", names.Accessor(), where)
+		return invalidName(mp, err, suggestNames(mp, taken))
+	}
+	return nil
+}
+
+func invalidName(mp modulePath, err error, suggestions []string) error {
+	if len(suggestions) == 0 {
+		return fmt.Errorf("invalid module name for %s: %w", mp.Rel, err)
+	}
+	dir := filepath.ToSlash(filepath.Dir(mp.Rel))
+	for i, s := range suggestions {
+		suggestions[i] = "//" + strings.TrimPrefix(dir+"/"+s+".lua", "./")
+	}
+	return fmt.Errorf("invalid module name for %s: %w\ntry one of:\n  %s", mp.Rel, err, strings.Join(suggestions, "\n  "))
+}
+
+// takenNames maps every registered class and every boot accessor to the
+// location that defines it.
+func takenNames(root string) (map[string]string, error) {
+	taken := make(map[string]string)
+
+	regs, err := scan.Registrations(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, reg := range regs {
+		taken[reg.Class] = fmt.Sprintf("%s:%d", reg.Path, reg.Line)
+	}
+
+	bootRel := strings.TrimPrefix(boot.Path, "//")
+	bootSrc, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(bootRel)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boot file: %w", err)
+	}
+	for _, a := range boot.Accessors(bootSrc) {
+		taken[a.Name] = fmt.Sprintf("%s:%d", bootRel, a.Line)
+	}
+	return taken, nil
+}
+
+// suggestNames proposes valid, unused file base names for the module at mp.
+func suggestNames(mp modulePath, taken map[string]string) []string {
+	base := mp.baseName()
+
+	// Turn separators into camel case, e.g. my-bag -> myBag, and move any
+	// leading digits to the end, e.g. 2fast -> fast2.
+	var b strings.Builder
+	upper := false
+	for _, r := range base {
+		switch {
+		case r < unicode.MaxASCII && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if upper && b.Len() > 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	clean := b.String()
+	digits := strings.TrimLeft(clean, "0123456789")
+	clean = digits + clean[:len(clean)-len(digits)]
+	if clean == "" {
+		clean = "module"
+	}
+
+	dir := filepath.Base(filepath.Dir(mp.Abs))
+	candidates := []string{clean}
+	if luaReserved[strings.ToLower(clean[:1])+clean[1:]] {
+		candidates = []string{clean + "Module"}
+	}
+	if taken != nil {
+		candidates = []string{
+			dir + strings.ToUpper(clean[:1]) + clean[1:],
+			clean + "2",
+			clean + "Module",
+		}
+	}
+
+	var out []string
+	for _, c := range candidates {
+		n, err := namesFromBase(c)
+		if err != nil || validIdentifier(n.ModuleNameLower) != nil || validIdentifier(n.ModuleName) != nil {
+			continue
+		}
+		if _, ok := taken[n.ModuleNameLower]; ok {
+			continue
+		}
+		if _, ok := taken[n.Accessor()]; ok {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
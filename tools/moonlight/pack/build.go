@@ -0,0 +1,43 @@
+package pack
+
+import (
+	"github.com/Cidan/Moonlight/tools/moonlight/changelog"
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+)
+
+// ChangelogFile is the name of the generated changelog in the package.
+const ChangelogFile = "CHANGELOG.md"
+
+// Build is everything that goes into a package of the repo at HEAD.
+type Build struct {
+	Meta     Meta
+	Revision vcs.Revision
+	Contents
+}
+
+// NewBuild reads .pkgmeta and collects the package contents for the repo at
+// root. Unless .pkgmeta sets a manual changelog, a changelog generated from
+// the git history is added.
+func NewBuild(root string) (*Build, error) {
+	rev, err := vcs.Head(root)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := LoadMeta(root)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := Collect(root, meta, Tokens(rev))
+	if err != nil {
+		return nil, err
+	}
+	if meta.ManualChangelog == nil {
+		c, err := changelog.Generate(root, "", "")
+		if err != nil {
+			return nil, err
+		}
+		c.Name = meta.PackageAs
+		contents.Add(File{Path: ChangelogFile, Dest: meta.dest(ChangelogFile), Content: c.Markdown()})
+	}
+	return &Build{Meta: meta, Revision: rev, Contents: contents}, nil
+}
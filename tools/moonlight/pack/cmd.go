@@ -0,0 +1,125 @@
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+	"github.com/spf13/cobra"
+)
+
+// NewPackageCmd creates and returns the package command.
+func NewPackageCmd() *cobra.Command {
+	var (
+		output string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "package",
+		Short: "Build the distributable addon zip",
+		Long: `Builds <package>-<version>.zip with a top level <package>/ folder, ready to be
+extracted into the AddOns folder. The version is taken from the latest git tag.
+
+Packaging is configured by a .pkgmeta file at the repo root, which supports the package-as,
+ignore, move-folders, manual-changelog and enable-nolib-creation: no keys. Without package-as,
+the package is named Moonlight.
+
+The @project-version@ and @project-date-iso@ tokens, along with @project-timestamp@,
+@project-hash@ and @project-abbreviated-hash@, are substituted in every .lua, .xml and .toc file.
+Dot files and the paths in the .pkgmeta ignore list are left out, as is anything ignored by
+git and any Lua or XML file that Moonlight.toc does not load. Assets, e.g. textures, are only
+included when code refers to them.
+
+Packager markers are applied to .lua, .xml and .toc files, in Lua (--@debug@),
+XML (<!--@debug@-->) and TOC (#@debug@) comment form:
+  @debug@ ... @end-debug@                    commented out
+  @non-debug@ ... @end-non-debug@            uncommented
+  @do-not-package@ ... @end-do-not-package@  removed
+Files left empty by the markers are not packaged, and Moonlight.toc entries for files that are
+not packaged are dropped.
+
+Archives are reproducible: building the same commit gives a byte identical zip, with files in
+a fixed order, every timestamp set to the commit time and fixed permissions. Next to the zip,
+a JSON manifest lists every file with its SHA-256 hash and the source commit, and SHA256SUMS
+holds the hashes of the zip and the manifest.
+
+Unless .pkgmeta sets manual-changelog, a CHANGELOG.md with the changes since the previous tag
+is generated from the git history and included in the package.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			b, err := NewBuild(root)
+			if err != nil {
+				return err
+			}
+			meta, rev, files := b.Meta, b.Revision, b.Files
+			for _, entry := range b.Dropped {
+				fmt.Printf("Dropped %s from %s.toc, the file is not packaged\n", entry, Name)
+			}
+
+			outDir, err := util.GetRepoPath(output)
+			if err != nil {
+				return err
+			}
+			archiveName := Archive(meta.PackageAs, rev.Version())
+			archive := filepath.Join(outDir, archiveName)
+			if dryRun {
+				fmt.Printf("Would package %d files into %s\n", len(files), archive)
+				for _, f := range files {
+					fmt.Printf("  %s\n", f.Dest)
+				}
+				return nil
+			}
+
+			dirty, err := vcs.Dirty(root)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				fmt.Printf("warning: the worktree has uncommitted changes, the archive can not be rebuilt from %s\n", rev.ShortHash())
+			}
+
+			var buf bytes.Buffer
+			if err := WriteZip(&buf, files, rev.Time); err != nil {
+				return err
+			}
+			manifest, err := NewManifest(meta.PackageAs, rev, dirty, files, buf.Bytes()).Bytes()
+			if err != nil {
+				return err
+			}
+			manifestName := ManifestName(meta.PackageAs, rev.Version())
+			sums := Checksums([]string{archiveName, manifestName}, [][]byte{buf.Bytes(), manifest})
+
+			if err := os.MkdirAll(outDir, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			artifacts := []struct {
+				path    string
+				content []byte
+			}{
+				{archive, buf.Bytes()},
+				{filepath.Join(outDir, manifestName), manifest},
+				{filepath.Join(outDir, "SHA256SUMS"), sums},
+			}
+			for _, a := range artifacts {
+				if err := os.WriteFile(a.path, a.content, 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", filepath.Base(a.path), err)
+				}
+			}
+			fmt.Printf("Packaged %d files into %s\n", len(files), archive)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "//.release", "Directory to write the archive to")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the files that would be packaged instead of writing the archive")
+
+	return cmd
+}
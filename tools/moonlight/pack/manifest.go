@@ -0,0 +1,87 @@
+package pack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+)
+
+// ManifestFile is a single file in the archive.
+type ManifestFile struct {
+	// Path is the path inside the archive, including the addon folder.
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a built archive and the commit it was built from, so
+// that reviewers can rebuild it and compare.
+type Manifest struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	CommitTime string `json:"commitTime"`
+	// Dirty is true when the worktree had uncommitted changes, in which case
+	// the archive can not be rebuilt from Commit.
+	Dirty   bool           `json:"dirty"`
+	Archive string         `json:"archive"`
+	SHA256  string         `json:"sha256"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestName returns the manifest file name for a package and version.
+func ManifestName(name, version string) string {
+	return fmt.Sprintf("%s-%s.json", name, version)
+}
+
+// NewManifest describes the archive of the named package built from files
+// at rev.
+func NewManifest(name string, rev vcs.Revision, dirty bool, files []File, archive []byte) Manifest {
+	m := Manifest{
+		Name:       name,
+		Version:    rev.Version(),
+		Commit:     rev.Hash,
+		CommitTime: rev.Time.UTC().Format(time.RFC3339),
+		Dirty:      dirty,
+		Archive:    Archive(name, rev.Version()),
+		SHA256:     sum(archive),
+		Files:      make([]ManifestFile, 0, len(files)),
+	}
+	for _, f := range files {
+		m.Files = append(m.Files, ManifestFile{
+			Path:   f.Dest,
+			Size:   len(f.Content),
+			SHA256: sum(f.Content),
+		})
+	}
+	return m
+}
+
+// Bytes renders the manifest as indented JSON.
+func (m Manifest) Bytes() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Checksums renders a SHA256SUMS file, in the format read by sha256sum -c,
+// for the named artifacts in the given order.
+func Checksums(names []string, contents [][]byte) []byte {
+	var b strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", sum(contents[i]), name)
+	}
+	return []byte(b.String())
+}
+
+func sum(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,213 @@
+package pack
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// block is a pair of packager markers, e.g. --@debug@ and --@end-debug@,
+// and how the region between them is rewritten when packaging.
+type block struct {
+	name        string
+	open, close *regexp.Regexp
+	// rewrite returns the replacement for a whole region, markers included.
+	// inner is the text between the markers.
+	rewrite func(inner string) string
+	// remove drops the region, along with the lines the markers are on
+	// when nothing else is on them.
+	remove bool
+}
+
+// luaBlocks handle the markers in Lua comment form. Debug regions are
+// turned into long comments, and non-debug regions, which are long
+// comments in the source, are turned back into code.
+var luaBlocks = []block{
+	{
+		name:   "do-not-package",
+		open:   regexp.MustCompile(`--@do-not-package@`),
+		close:  regexp.MustCompile(`--@end-do-not-package@`),
+		remove: true,
+	},
+	{
+		name:  "debug",
+		open:  regexp.MustCompile(`--@debug@`),
+		close: regexp.MustCompile(`--@end-debug@`),
+		rewrite: func(inner string) string {
+			eq := longBracketEquals(inner)
+			return "--[" + eq + "[@debug@" + inner + "--@end-debug@]" + eq + "]"
+		},
+	},
+	{
+		name:  "non-debug",
+		open:  regexp.MustCompile(`--(?:\[=*\[)?@non-debug@`),
+		close: regexp.MustCompile(`--@end-non-debug@(?:\]=*\])?`),
+		rewrite: func(inner string) string {
+			return "--@non-debug@" + inner + "--@end-non-debug@"
+		},
+	},
+}
+
+// xmlBlocks handle the markers in XML comment form.
+var xmlBlocks = []block{
+	{
+		name:   "do-not-package",
+		open:   regexp.MustCompile(`<!--@do-not-package@-->`),
+		close:  regexp.MustCompile(`<!--@end-do-not-package@-->`),
+		remove: true,
+	},
+	{
+		name:  "debug",
+		open:  regexp.MustCompile(`<!--@debug@-->`),
+		close: regexp.MustCompile(`<!--@end-debug@-->`),
+		rewrite: func(inner string) string {
+			return "<!--@debug@" + inner + "@end-debug@-->"
+		},
+	},
+	{
+		name:  "non-debug",
+		open:  regexp.MustCompile(`<!--@non-debug@(?:-->)?`),
+		close: regexp.MustCompile(`(?:<!--)?@end-non-debug@-->`),
+		rewrite: func(inner string) string {
+			return "<!--@non-debug@-->" + inner + "<!--@end-non-debug@-->"
+		},
+	},
+}
+
+// tocBlocks handle the markers in TOC comment form, which work on whole
+// lines: debug lines are commented out with "# " and non-debug lines have
+// their leading "#" removed.
+var tocBlocks = []block{
+	{
+		name:   "do-not-package",
+		open:   regexp.MustCompile(`(?m)^#@do-not-package@[ \t]*$`),
+		close:  regexp.MustCompile(`(?m)^#@end-do-not-package@[ \t]*$`),
+		remove: true,
+	},
+	{
+		name:  "debug",
+		open:  regexp.MustCompile(`(?m)^#@debug@[ \t]*$`),
+		close: regexp.MustCompile(`(?m)^#@end-debug@[ \t]*$`),
+		rewrite: func(inner string) string {
+			return "#@debug@" + mapLines(inner, func(line string) string {
+				return "# " + line
+			}) + "#@end-debug@"
+		},
+	},
+	{
+		name:  "non-debug",
+		open:  regexp.MustCompile(`(?m)^#@non-debug@[ \t]*$`),
+		close: regexp.MustCompile(`(?m)^#@end-non-debug@[ \t]*$`),
+		rewrite: func(inner string) string {
+			return "#@non-debug@" + mapLines(inner, func(line string) string {
+				line = strings.TrimPrefix(line, "#")
+				return strings.TrimPrefix(line, " ")
+			}) + "#@end-non-debug@"
+		},
+	},
+}
+
+// StripMarkers applies the packager markers in a .lua, .xml or .toc file
+// at rel: do-not-package regions are removed, debug regions are commented
+// out and non-debug regions are enabled. Other files are returned as is.
+func StripMarkers(rel string, content []byte) ([]byte, error) {
+	var blocks []block
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".lua":
+		blocks = luaBlocks
+	case ".xml":
+		blocks = xmlBlocks
+	case ".toc":
+		blocks = tocBlocks
+	default:
+		return content, nil
+	}
+
+	src := string(content)
+	for _, b := range blocks {
+		var err error
+		if src, err = b.apply(src); err != nil {
+			return nil, fmt.Errorf("%s:%w", rel, err)
+		}
+	}
+	return []byte(src), nil
+}
+
+// apply rewrites every region of the block in src. Regions may not nest
+// or be left open.
+func (b block) apply(src string) (string, error) {
+	opens := b.open.FindAllStringIndex(src, -1)
+	closes := b.close.FindAllStringIndex(src, -1)
+	if len(opens) == 0 && len(closes) == 0 {
+		return src, nil
+	}
+
+	var out strings.Builder
+	last := 0
+	for i, open := range opens {
+		if i >= len(closes) || closes[i][0] < open[1] {
+			return "", fmt.Errorf("%d: @%s@ is not closed by @end-%s@", lineAt(src, open[0]), b.name, b.name)
+		}
+		if i+1 < len(opens) && opens[i+1][0] < closes[i][0] {
+			return "", fmt.Errorf("%d: @%s@ regions can not be nested", lineAt(src, opens[i+1][0]), b.name)
+		}
+		start, end := open[0], closes[i][1]
+		if b.remove {
+			start, end = wholeLines(src, start, end)
+			out.WriteString(src[last:start])
+		} else {
+			out.WriteString(src[last:start])
+			out.WriteString(b.rewrite(src[open[1]:closes[i][0]]))
+		}
+		last = end
+	}
+	if len(closes) > len(opens) {
+		return "", fmt.Errorf("%d: @end-%s@ without a matching @%s@", lineAt(src, closes[len(opens)][0]), b.name, b.name)
+	}
+	out.WriteString(src[last:])
+	return out.String(), nil
+}
+
+// wholeLines widens the region [start, end) to the full lines it is on,
+// as long as the lines hold nothing but the region and whitespace.
+func wholeLines(src string, start, end int) (int, int) {
+	lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+	if strings.TrimSpace(src[lineStart:start]) != "" {
+		return start, end
+	}
+	lineEnd := len(src)
+	if i := strings.IndexByte(src[end:], '\n'); i != -1 {
+		lineEnd = end + i + 1
+	}
+	if strings.TrimSpace(src[end:lineEnd]) != "" {
+		return start, end
+	}
+	return lineStart, lineEnd
+}
+
+// mapLines applies fn to every full line in text, which starts right after
+// an opening marker and ends right before a closing one.
+func mapLines(text string, fn func(string) string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines)-1; i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			lines[i] = fn(lines[i])
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// longBracketEquals returns the "=" padding for a long comment that can hold
+// text without being closed early.
+func longBracketEquals(text string) string {
+	eq := "==="
+	for strings.Contains(text, "]"+eq+"]") {
+		eq += "="
+	}
+	return eq
+}
+
+func lineAt(src string, offset int) int {
+	return strings.Count(src[:offset], "\n") + 1
+}
@@ -0,0 +1,132 @@
+package pack
+
+import "testing"
+
+func TestStripMarkers(t *testing.T) {
+	tests := []struct {
+		name string
+		rel  string
+		in   string
+		want string
+	}{
+		{
+			name: "lua debug",
+			rel:  "bag/bag.lua",
+			in:   "a()\n--@debug@\nprint(1)\n--@end-debug@\nb()\n",
+			want: "a()\n--[===[@debug@\nprint(1)\n--@end-debug@]===]\nb()\n",
+		},
+		{
+			name: "lua debug holding a long bracket",
+			rel:  "bag/bag.lua",
+			in:   "--@debug@\nlocal s = [===[x]===]\n--@end-debug@\n",
+			want: "--[====[@debug@\nlocal s = [===[x]===]\n--@end-debug@]====]\n",
+		},
+		{
+			name: "lua non-debug",
+			rel:  "bag/bag.lua",
+			in:   "--[===[@non-debug@\nrelease()\n--@end-non-debug@]===]\n",
+			want: "--@non-debug@\nrelease()\n--@end-non-debug@\n",
+		},
+		{
+			name: "lua do-not-package",
+			rel:  "bag/bag.lua",
+			in:   "a()\n--@do-not-package@\ndev()\n--@end-do-not-package@\nb()\n",
+			want: "a()\nb()\n",
+		},
+		{
+			name: "lua do-not-package within a line",
+			rel:  "bag/bag.lua",
+			in:   "x = 1 --@do-not-package@ dev() --@end-do-not-package@\n",
+			want: "x = 1 \n",
+		},
+		{
+			name: "lua upper case extension",
+			rel:  "bag/Bag.LUA",
+			in:   "--@do-not-package@\ndev()\n--@end-do-not-package@\n",
+			want: "",
+		},
+		{
+			name: "xml debug",
+			rel:  "frames/frames.xml",
+			in:   "<Ui>\n<!--@debug@-->\n<Frame/>\n<!--@end-debug@-->\n</Ui>\n",
+			want: "<Ui>\n<!--@debug@\n<Frame/>\n@end-debug@-->\n</Ui>\n",
+		},
+		{
+			name: "xml non-debug",
+			rel:  "frames/frames.xml",
+			in:   "<Ui>\n<!--@non-debug@\n<Frame/>\n@end-non-debug@-->\n</Ui>\n",
+			want: "<Ui>\n<!--@non-debug@-->\n<Frame/>\n<!--@end-non-debug@-->\n</Ui>\n",
+		},
+		{
+			name: "xml do-not-package",
+			rel:  "frames/frames.xml",
+			in:   "<Ui>\n  <!--@do-not-package@-->\n  <Script file=\"dev.lua\"/>\n  <!--@end-do-not-package@-->\n</Ui>\n",
+			want: "<Ui>\n</Ui>\n",
+		},
+		{
+			name: "toc debug",
+			rel:  "Moonlight.toc",
+			in:   "a.lua\n#@debug@\ndebug.lua\n\n#@end-debug@\nb.lua\n",
+			want: "a.lua\n#@debug@\n# debug.lua\n\n#@end-debug@\nb.lua\n",
+		},
+		{
+			name: "toc non-debug",
+			rel:  "Moonlight.toc",
+			in:   "#@non-debug@\n# release.lua\n#other.lua\n#@end-non-debug@\n",
+			want: "#@non-debug@\nrelease.lua\nother.lua\n#@end-non-debug@\n",
+		},
+		{
+			name: "toc do-not-package",
+			rel:  "Moonlight.toc",
+			in:   "a.lua\n#@do-not-package@\ndev.lua\n#@end-do-not-package@\nb.lua\n",
+			want: "a.lua\nb.lua\n",
+		},
+		{
+			name: "toc markers within a line",
+			rel:  "Moonlight.toc",
+			in:   "a.lua #@debug@\n",
+			want: "a.lua #@debug@\n",
+		},
+		{
+			name: "other files",
+			rel:  "README.md",
+			in:   "--@debug@\n",
+			want: "--@debug@\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripMarkers(tt.rel, []byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("StripMarkers(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripMarkersErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rel  string
+		in   string
+		want string
+	}{
+		{"lua unclosed", "a.lua", "x()\n--@debug@\ny()\n", "a.lua:2: @debug@ is not closed by @end-debug@"},
+		{"lua closed by another marker", "a.lua", "--@debug@\n--@end-do-not-package@\n", "a.lua:2: @end-do-not-package@ without a matching @do-not-package@"},
+		{"lua nested", "a.lua", "--@debug@\n--@debug@\n--@end-debug@\n--@end-debug@\n", "a.lua:2: @debug@ regions can not be nested"},
+		{"lua close without open", "a.lua", "x()\n--@end-non-debug@]===]\n", "a.lua:2: @end-non-debug@ without a matching @non-debug@"},
+		{"xml unclosed", "a.xml", "<Ui>\n<!--@do-not-package@-->\n</Ui>\n", "a.xml:2: @do-not-package@ is not closed by @end-do-not-package@"},
+		{"toc unclosed", "a.toc", "a.lua\n#@non-debug@\n#b.lua\n", "a.toc:2: @non-debug@ is not closed by @end-non-debug@"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StripMarkers(tt.rel, []byte(tt.in))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("StripMarkers(%q) = %v, want %q", tt.in, err, tt.want)
+			}
+		})
+	}
+}
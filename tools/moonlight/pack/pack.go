@@ -0,0 +1,304 @@
+// Package pack builds the distributable addon archive.
+package pack
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// Name is the name of the addon and its TOC file, and the default name of
+// the addon folder inside the archive.
+const Name = "Moonlight"
+
+// DevIgnore lists the files that are never packaged, on top of the ignore
+// list in .pkgmeta. Its patterns match a file or directory name at any
+// depth. Like other WoW packagers, dot files are always left out.
+var DevIgnore = []string{".*"}
+
+// codeExts are the file types the client loads. Everything else is an asset
+// and is only packaged when code refers to it.
+var codeExts = []string{".lua", ".xml", ".toc"}
+
+// fileMode is the permission every file in the archive gets, so that
+// archives do not depend on the umask of the machine that built them.
+const fileMode fs.FileMode = 0644
+
+// File is a single file in the package.
+type File struct {
+	// Path is the repo relative, forward slash path of the file.
+	Path string
+	// Dest is the path of the file in the archive, starting with its top
+	// level folder.
+	Dest    string
+	Content []byte
+}
+
+// Archive returns the archive file name for a package and version.
+func Archive(name, version string) string {
+	return fmt.Sprintf("%s-%s.zip", name, version)
+}
+
+// Tokens returns the keyword substitutions for a revision.
+func Tokens(rev vcs.Revision) map[string]string {
+	return map[string]string{
+		"@project-version@":          rev.Version(),
+		"@project-date-iso@":         rev.Time.UTC().Format(time.RFC3339),
+		"@project-timestamp@":        fmt.Sprint(rev.Time.Unix()),
+		"@project-hash@":             rev.Hash,
+		"@project-abbreviated-hash@": rev.ShortHash(),
+	}
+}
+
+// Contents is the set of files that make up a package.
+type Contents struct {
+	// Files is every file in the package, sorted by archive path.
+	Files []File
+	// Dropped lists the TOC entries that were removed because their file
+	// is not packaged.
+	Dropped []string
+}
+
+// Walk calls fn for every regular file under root that can be packaged,
+// that is every file that is not ignored by meta or by git, with its path
+// and its repo relative, forward slash path. Ignored directories are not
+// walked at all.
+func Walk(root string, meta Meta, fn func(p, rel string) error) error {
+	var patterns []gitignore.Pattern
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." {
+			if meta.ignored(rel) || gitignore.NewMatcher(patterns).Match(strings.Split(rel, "/"), d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			dirPatterns, err := readGitignore(p, rel)
+			if err != nil {
+				return err
+			}
+			patterns = append(patterns, dirPatterns...)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(p, rel)
+	})
+}
+
+// Collect gathers every file that belongs in the package for the repo at
+// root, laid out as described by meta. Dev files, files ignored by meta or
+// git and Lua and XML files that the TOC does not load are skipped.
+// Packager markers are applied and tokens are substituted in code files,
+// and files that are left empty by the markers are skipped as well. Assets
+// are only included when a code file refers to them, and the manual
+// changelog is always included. Finally, TOC entries for files that are not
+// packaged are dropped.
+func Collect(root string, meta Meta, tokens map[string]string) (Contents, error) {
+	tocName := Name + ".toc"
+	tocSrc, err := os.ReadFile(filepath.Join(root, tocName))
+	if err != nil {
+		return Contents{}, fmt.Errorf("failed to read toc file: %w", err)
+	}
+	tocSrc, err = StripMarkers(tocName, tocSrc)
+	if err != nil {
+		return Contents{}, err
+	}
+	loadedFiles, err := toc.Parse(tocSrc).LoadedFiles(root)
+	if err != nil {
+		return Contents{}, err
+	}
+	loaded := make(map[string]bool)
+	for _, rel := range loadedFiles {
+		loaded[rel] = true
+	}
+
+	var code, assets []File
+
+	err = Walk(root, meta, func(p, rel string) error {
+		if isCode(rel) && rel != tocName && !loaded[rel] {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		if !isCode(rel) {
+			assets = append(assets, File{Path: rel, Dest: meta.dest(rel), Content: content})
+			return nil
+		}
+		if rel == tocName {
+			content = tocSrc
+		} else if content, err = StripMarkers(rel, content); err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(content)) == 0 {
+			// The whole file is marked do-not-package.
+			return nil
+		}
+		code = append(code, File{Path: rel, Dest: meta.dest(rel), Content: substitute(content, tokens)})
+		return nil
+	})
+	if err != nil {
+		return Contents{}, fmt.Errorf("failed to collect package files: %w", err)
+	}
+
+	files := code
+	refs := references(code)
+	for _, asset := range assets {
+		if refs.mentions(asset.Dest) || (meta.ManualChangelog != nil && asset.Path == meta.ManualChangelog.Filename) {
+			files = append(files, asset)
+		}
+	}
+	if meta.ManualChangelog != nil && !slices.ContainsFunc(files, func(f File) bool { return f.Path == meta.ManualChangelog.Filename }) {
+		return Contents{}, fmt.Errorf("manual-changelog %s does not exist or is ignored", meta.ManualChangelog.Filename)
+	}
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Dest, b.Dest) })
+
+	contents := Contents{Files: files}
+	contents.dropEntries(tocName)
+	return contents, nil
+}
+
+// Add adds a file to the package, keeping the files sorted.
+func (c *Contents) Add(f File) {
+	i, _ := slices.BinarySearchFunc(c.Files, f.Dest, func(f File, dest string) int {
+		return strings.Compare(f.Dest, dest)
+	})
+	c.Files = slices.Insert(c.Files, i, f)
+}
+
+// dropEntries removes the entries of the TOC file at rel whose file is not
+// part of the package.
+func (c *Contents) dropEntries(rel string) {
+	packaged := make(map[string]bool, len(c.Files))
+	for _, f := range c.Files {
+		packaged[f.Path] = true
+	}
+	for i, f := range c.Files {
+		if f.Path != rel {
+			continue
+		}
+		t := toc.Parse(f.Content)
+		for _, entry := range t.Entries() {
+			if !packaged[entry] && t.Remove(entry) {
+				c.Dropped = append(c.Dropped, entry)
+			}
+		}
+		c.Files[i].Content = t.Bytes()
+	}
+}
+
+// readGitignore parses the .gitignore file in dir, if there is one. rel is
+// the repo relative path of dir.
+func readGitignore(dir, rel string) ([]gitignore.Pattern, error) {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domain []string
+	if rel != "." {
+		domain = strings.Split(rel, "/")
+	}
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+	return patterns, scanner.Err()
+}
+
+func isCode(rel string) bool {
+	return slices.Contains(codeExts, strings.ToLower(path.Ext(rel)))
+}
+
+func substitute(content []byte, tokens map[string]string) []byte {
+	if len(tokens) == 0 || !bytes.Contains(content, []byte("@project-")) {
+		return content
+	}
+	var pairs []string
+	for token, value := range tokens {
+		pairs = append(pairs, token, value)
+	}
+	return []byte(strings.NewReplacer(pairs...).Replace(string(content)))
+}
+
+// codeRefs is the combined text of every code file, normalized so that
+// asset paths can be found regardless of case and slash style.
+type codeRefs string
+
+func references(code []File) codeRefs {
+	var b strings.Builder
+	for _, f := range code {
+		b.WriteString(strings.ToLower(strings.ReplaceAll(string(f.Content), `\`, "/")))
+		b.WriteString("\n")
+	}
+	return codeRefs(b.String())
+}
+
+// mentions reports if code refers to the asset at dest, its path in the
+// archive, through its addon path, e.g.
+// interface/addons/moonlight/assets/textures/parchment. The file extension
+// is optional, as it is for the client.
+func (r codeRefs) mentions(dest string) bool {
+	stem := strings.TrimSuffix(dest, path.Ext(dest))
+	return strings.Contains(string(r), strings.ToLower("addons/"+stem))
+}
+
+// WriteZip writes files into a zip archive at their Dest path. The archive
+// only depends on its input: files are written in the given order, every
+// file gets the same permissions, and every timestamp is set to modTime,
+// which should be the commit time.
+func WriteZip(w io.Writer, files []File, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		header := &zip.FileHeader{
+			Name:     f.Dest,
+			Method:   zip.Deflate,
+			Modified: modTime.UTC(),
+		}
+		header.SetMode(fileMode)
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", f.Path, err)
+		}
+		if _, err := fw.Write(f.Content); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", f.Path, err)
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,246 @@
+package pack
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetaFile is the name of the packaging config at the repo root. It uses
+// the .pkgmeta format read by other WoW packaging tools.
+const MetaFile = ".pkgmeta"
+
+// markupTypes are the changelog markup types .pkgmeta allows.
+var markupTypes = []string{"markdown", "bbcode", "text"}
+
+// Move is a move-folders entry: everything under From, a path in the
+// package that starts with the package name, is packaged as a separate
+// top level folder named To.
+type Move struct {
+	From string
+	To   string
+	// line is the line of the entry in .pkgmeta.
+	line int
+}
+
+// Changelog is the manual-changelog entry.
+type Changelog struct {
+	// Filename is the repo relative path of the changelog.
+	Filename   string
+	MarkupType string
+}
+
+// Meta is the supported subset of .pkgmeta.
+type Meta struct {
+	// PackageAs is the name of the addon folder in the archive.
+	PackageAs string
+	// Ignore holds extra paths to leave out of the package. Each pattern
+	// matches a path from the repo root, and a pattern that matches a
+	// directory leaves out everything under it.
+	Ignore          []string
+	MoveFolders     []Move
+	ManualChangelog *Changelog
+}
+
+// DefaultMeta is used when the repo has no .pkgmeta file.
+func DefaultMeta() Meta {
+	return Meta{PackageAs: Name}
+}
+
+// LoadMeta reads the .pkgmeta file at the repo root, or returns the
+// defaults if there is none.
+func LoadMeta(root string) (Meta, error) {
+	content, err := os.ReadFile(filepath.Join(root, MetaFile))
+	if os.IsNotExist(err) {
+		return DefaultMeta(), nil
+	}
+	if err != nil {
+		return Meta{}, fmt.Errorf("failed to read %s: %w", MetaFile, err)
+	}
+	return ParseMeta(content)
+}
+
+// metaError is an error at a line of the .pkgmeta file.
+func metaError(node *yaml.Node, format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", MetaFile, node.Line, fmt.Sprintf(format, args...))
+}
+
+// ParseMeta parses the contents of a .pkgmeta file. Keys outside of the
+// supported subset are errors rather than being silently ignored, so that
+// the package never differs from what the file asks for.
+func ParseMeta(content []byte) (Meta, error) {
+	meta := DefaultMeta()
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return Meta{}, fmt.Errorf("%s: %w", MetaFile, err)
+	}
+	if len(doc.Content) == 0 {
+		return meta, nil
+	}
+	top := doc.Content[0]
+	if top.Kind != yaml.MappingNode {
+		return Meta{}, metaError(top, "expected a mapping of keys to values")
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(top.Content); i += 2 {
+		key, value := top.Content[i], top.Content[i+1]
+		if seen[key.Value] {
+			return Meta{}, metaError(key, "%s is set more than once", key.Value)
+		}
+		seen[key.Value] = true
+
+		var err error
+		switch key.Value {
+		case "package-as":
+			meta.PackageAs, err = parseFolderName(value, key.Value)
+		case "ignore":
+			meta.Ignore, err = parseIgnore(value)
+		case "move-folders":
+			meta.MoveFolders, err = parseMoveFolders(value)
+		case "manual-changelog":
+			meta.ManualChangelog, err = parseChangelog(value)
+		case "enable-nolib-creation":
+			var enabled bool
+			if value.Kind != yaml.ScalarNode || value.Decode(&enabled) != nil {
+				return Meta{}, metaError(value, "enable-nolib-creation must be yes or no")
+			}
+			if enabled {
+				err = metaError(value, "nolib packages are not supported, set enable-nolib-creation to no")
+			}
+		default:
+			err = metaError(key, "unknown key %q, supported keys are package-as, ignore, move-folders, manual-changelog and enable-nolib-creation", key.Value)
+		}
+		if err != nil {
+			return Meta{}, err
+		}
+	}
+
+	for _, move := range meta.MoveFolders {
+		if !strings.HasPrefix(move.From, meta.PackageAs+"/") {
+			return Meta{}, fmt.Errorf("%s:%d: move-folders source %s must be inside the package folder %s", MetaFile, move.line, move.From, meta.PackageAs)
+		}
+	}
+	return meta, nil
+}
+
+func parseFolderName(node *yaml.Node, key string) (string, error) {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return "", metaError(node, "%s must be a folder name", key)
+	}
+	if strings.ContainsAny(node.Value, `/\`) || node.Value == "." || node.Value == ".." {
+		return "", metaError(node, "%s must be a single folder name, got %q", key, node.Value)
+	}
+	return node.Value, nil
+}
+
+func parseIgnore(node *yaml.Node) ([]string, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, metaError(node, "ignore must be a list of paths")
+	}
+	var ignore []string
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.Value == "" {
+			return nil, metaError(item, "ignore entries must be paths")
+		}
+		pattern := strings.Trim(strings.TrimPrefix(strings.ReplaceAll(item.Value, `\`, "/"), "./"), "/")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, metaError(item, "invalid ignore pattern %q: %v", item.Value, err)
+		}
+		ignore = append(ignore, pattern)
+	}
+	return ignore, nil
+}
+
+func parseMoveFolders(node *yaml.Node) ([]Move, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, metaError(node, "move-folders must map package paths to folder names")
+	}
+	var moves []Move
+	for i := 0; i < len(node.Content); i += 2 {
+		from, to := node.Content[i], node.Content[i+1]
+		if from.Kind != yaml.ScalarNode || from.Value == "" {
+			return nil, metaError(from, "move-folders sources must be paths")
+		}
+		name, err := parseFolderName(to, "move-folders destination")
+		if err != nil {
+			return nil, err
+		}
+		source := strings.TrimSuffix(strings.ReplaceAll(from.Value, `\`, "/"), "/")
+		if slices.ContainsFunc(moves, func(m Move) bool { return m.From == source }) {
+			return nil, metaError(from, "%s is moved more than once", source)
+		}
+		moves = append(moves, Move{From: source, To: name, line: from.Line})
+	}
+	return moves, nil
+}
+
+func parseChangelog(node *yaml.Node) (*Changelog, error) {
+	changelog := &Changelog{MarkupType: "text"}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		changelog.Filename = node.Value
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return nil, metaError(value, "manual-changelog %s must be a string", key.Value)
+			}
+			switch key.Value {
+			case "filename":
+				changelog.Filename = value.Value
+			case "markup-type":
+				if !slices.Contains(markupTypes, value.Value) {
+					return nil, metaError(value, "unknown markup-type %q, expected one of %s", value.Value, strings.Join(markupTypes, ", "))
+				}
+				changelog.MarkupType = value.Value
+			default:
+				return nil, metaError(key, "unknown manual-changelog key %q, supported keys are filename and markup-type", key.Value)
+			}
+		}
+	default:
+		return nil, metaError(node, "manual-changelog must be a file name or a mapping with filename and markup-type")
+	}
+	if changelog.Filename == "" {
+		return nil, metaError(node, "manual-changelog needs a filename")
+	}
+	changelog.Filename = strings.ReplaceAll(changelog.Filename, `\`, "/")
+	return changelog, nil
+}
+
+// ignored reports if the repo relative path rel, or a directory it is in,
+// is ignored. The built in DevIgnore patterns match a file or directory
+// name at any depth, the .pkgmeta ignore patterns a path from the repo
+// root.
+func (m Meta) ignored(rel string) bool {
+	for dir := rel; dir != "."; dir = path.Dir(dir) {
+		for _, pattern := range DevIgnore {
+			if ok, _ := path.Match(pattern, path.Base(dir)); ok {
+				return true
+			}
+		}
+		for _, pattern := range m.Ignore {
+			if ok, _ := path.Match(pattern, dir); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dest returns the path of the repo relative file rel in the archive,
+// including its top level folder.
+func (m Meta) dest(rel string) string {
+	dest := m.PackageAs + "/" + rel
+	for _, move := range m.MoveFolders {
+		if strings.HasPrefix(dest, move.From+"/") {
+			return move.To + "/" + strings.TrimPrefix(dest, move.From+"/")
+		}
+	}
+	return dest
+}
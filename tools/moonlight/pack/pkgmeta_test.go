@@ -0,0 +1,31 @@
+package pack
+
+import "testing"
+
+func TestMetaIgnored(t *testing.T) {
+	meta, err := ParseMeta([]byte("ignore:\n  - libs\n  - ./docs/\n  - '*.md'\n  - media/*.psd\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"libs", true},
+		{"libs/LibStub/LibStub.lua", true},
+		{"foo/libs/x.lua", false},
+		{"docs/index.html", true},
+		{"README.md", true},
+		{"foo/README.md", false},
+		{"media/logo.psd", true},
+		{"media/logo.tga", false},
+		{".github/workflows/ci.yml", true},
+		{"foo/.editorconfig", true},
+		{"core/core.lua", false},
+	}
+	for _, tt := range tests {
+		if got := meta.ignored(tt.rel); got != tt.want {
+			t.Errorf("ignored(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package release
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewReleaseCmd creates and returns the release command with its
+// subcommands.
+func NewReleaseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "Tag and publish releases",
+	}
+
+	cmd.AddCommand(newTagCmd())
+	cmd.AddCommand(newPublishCmd())
+
+	return cmd
+}
@@ -0,0 +1,415 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/pack"
+	"github.com/Cidan/Moonlight/tools/moonlight/semver"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// EndpointEnv and TokenEnv name the environment variables that set the
+	// publish endpoint and token when the flags are not given.
+	EndpointEnv = "MOONLIGHT_PUBLISH_ENDPOINT"
+	TokenEnv    = "MOONLIGHT_PUBLISH_TOKEN"
+)
+
+// Metadata describes an uploaded release. It is sent as the JSON
+// "metadata" part of the upload.
+type Metadata struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	DisplayName string `json:"displayName"`
+	// ReleaseType is release, beta or alpha, derived from the pre-release
+	// part of the version.
+	ReleaseType   string `json:"releaseType"`
+	Changelog     string `json:"changelog"`
+	ChangelogType string `json:"changelogType"`
+	// GameVersions are the game versions of Interfaces, e.g. 11.1.5.
+	GameVersions []string `json:"gameVersions"`
+	Interfaces   []int    `json:"interfaces"`
+	Commit       string   `json:"commit"`
+	SHA256       string   `json:"sha256"`
+}
+
+// ReleaseType returns the release type of a version: alpha when the
+// pre-release mentions alpha, beta for any other pre-release and release
+// otherwise.
+func ReleaseType(v semver.Version) string {
+	switch {
+	case v.Pre == "":
+		return "release"
+	case strings.Contains(strings.ToLower(v.Pre), "alpha"):
+		return "alpha"
+	default:
+		return "beta"
+	}
+}
+
+// Upload is a release upload to a distribution API: a multipart POST of
+// the metadata and the archive, authenticated with a bearer token.
+type Upload struct {
+	Endpoint string
+	Token    string
+	Metadata Metadata
+	// Archive is the file name of the zip.
+	Archive string
+	Zip     []byte
+}
+
+// Request builds the HTTP request of the upload. A new request is built for
+// every attempt, as the body is consumed by sending it.
+func (u *Upload) Request(ctx context.Context) (*http.Request, error) {
+	body, contentType, err := u.body()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+u.Token)
+	req.Header.Set("User-Agent", "moonlight")
+	return req, nil
+}
+
+func (u *Upload) body() ([]byte, string, error) {
+	metadata, err := json.Marshal(u.Metadata)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode release metadata: %w", err)
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="metadata"`)
+	header.Set("Content-Type", "application/json")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+	part.Write(metadata)
+
+	header = textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, u.Archive))
+	header.Set("Content-Type", "application/zip")
+	part, err = w.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+	part.Write(u.Zip)
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to encode upload: %w", err)
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// Describe writes the request that would be sent, with the token redacted
+// and the archive summarized by its size and hash.
+func (u *Upload) Describe(w io.Writer) error {
+	metadata, err := json.MarshalIndent(u.Metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode release metadata: %w", err)
+	}
+	sum := sha256.Sum256(u.Zip)
+	token := "<none>"
+	if u.Token != "" {
+		token = "<redacted>"
+	}
+	fmt.Fprintf(w, "POST %s\n", u.Endpoint)
+	fmt.Fprintf(w, "Authorization: Bearer %s\n", token)
+	fmt.Fprintf(w, "Content-Type: multipart/form-data\n\n")
+	fmt.Fprintf(w, "metadata (application/json):\n%s\n\n", metadata)
+	fmt.Fprintf(w, "file (application/zip): %s, %d bytes, sha256 %s\n", u.Archive, len(u.Zip), hex.EncodeToString(sum[:]))
+	return nil
+}
+
+// retryDelay is the delay before the first retry, doubled for every
+// retry after it.
+var retryDelay = time.Second
+
+// Publish sends the upload, retrying up to retries times when it is certain
+// that the upload was not accepted: the connection could not be made, or
+// the server answered 429 or 503. A Retry-After header in seconds overrides
+// the delay. An upload is not idempotent, so other errors, such as a
+// timeout once the request was sent, are returned at once rather than
+// risking a duplicate release. The response body of a successful upload is
+// returned.
+func Publish(ctx context.Context, client *http.Client, u *Upload, retries int, log func(string)) ([]byte, error) {
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		body, wait, err := send(ctx, client, u)
+		if err == nil {
+			return body, nil
+		}
+		if wait < 0 || attempt >= retries {
+			return nil, err
+		}
+		if wait == 0 {
+			wait = delay
+		}
+		log(fmt.Sprintf("%v, retrying in %s (%d/%d)", err, wait, attempt+1, retries))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// send makes a single attempt. On failure, wait is negative when the error
+// is final, and otherwise the delay the server asked for, or zero.
+func send(ctx context.Context, client *http.Client, u *Upload) (body []byte, wait time.Duration, err error) {
+	req, err := u.Request(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, -1, ctx.Err()
+		}
+		// Only a failed dial proves that the server never saw the request.
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, 0, fmt.Errorf("failed to upload: %w", err)
+		}
+		return nil, -1, fmt.Errorf("failed to upload, check the release on the site before publishing again: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, -1, fmt.Errorf("failed to read upload response, check the release on the site before publishing again: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return body, 0, nil
+	}
+
+	err = fmt.Errorf("upload failed with %s", resp.Status)
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		err = fmt.Errorf("upload failed with %s: %s", resp.Status, msg)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, -1, err
+	}
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+	return nil, wait, err
+}
+
+// NewUpload builds the package of the repo at root and describes its
+// upload to endpoint. HEAD must be tagged with a version, unless untagged
+// is set: the release is then an alpha, versioned with the git describe
+// string of HEAD. When HEAD is not tagged and untagged is not set, or the
+// worktree is dirty, an error is returned unless force is set, in which
+// case warn is called instead.
+func NewUpload(root, endpoint, token string, untagged, force bool, warn func(string)) (*Upload, error) {
+	_, headTags, err := vcs.TagNames(root)
+	if err != nil {
+		return nil, err
+	}
+	var version semver.Version
+	tagged := false
+	for _, tag := range headTags {
+		if v, ok := semver.Parse(tag); ok && (!tagged || v.Compare(version) > 0) {
+			version, tagged = v, true
+		}
+	}
+	dirty, err := vcs.Dirty(root)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	if !tagged && !untagged {
+		problems = append(problems, "HEAD is not tagged with a version, tag it with release tag first or pass --untagged to publish an alpha")
+	}
+	if dirty {
+		problems = append(problems, "the worktree has uncommitted changes, the archive can not be rebuilt from HEAD")
+	}
+	for _, problem := range problems {
+		if !force {
+			return nil, fmt.Errorf("%s", problem)
+		}
+		warn(problem)
+	}
+
+	b, err := pack.NewBuild(root)
+	if err != nil {
+		return nil, err
+	}
+	name, releaseType := version.String(), ReleaseType(version)
+	if !tagged {
+		name, releaseType = b.Revision.Version(), "alpha"
+	}
+	var zip bytes.Buffer
+	if err := pack.WriteZip(&zip, b.Files, b.Revision.Time); err != nil {
+		return nil, err
+	}
+
+	tocFile, changelog := pack.Name+".toc", pack.ChangelogFile
+	changelogType := "markdown"
+	if b.Meta.ManualChangelog != nil {
+		changelog, changelogType = b.Meta.ManualChangelog.Filename, b.Meta.ManualChangelog.MarkupType
+	}
+	var interfaces []int
+	var notes string
+	for _, f := range b.Files {
+		switch f.Path {
+		case tocFile:
+			if interfaces, err = toc.Parse(f.Content).Interfaces(); err != nil {
+				return nil, fmt.Errorf("%s: %w", tocFile, err)
+			}
+		case changelog:
+			notes = string(f.Content)
+		}
+	}
+	if interfaces == nil {
+		return nil, fmt.Errorf("%s is not packaged", tocFile)
+	}
+	gameVersions := make([]string, len(interfaces))
+	for i, iface := range interfaces {
+		gameVersions[i] = toc.GameVersion(iface)
+	}
+
+	sum := sha256.Sum256(zip.Bytes())
+	archive := pack.Archive(b.Meta.PackageAs, b.Revision.Version())
+	return &Upload{
+		Endpoint: endpoint,
+		Token:    token,
+		Archive:  archive,
+		Zip:      zip.Bytes(),
+		Metadata: Metadata{
+			Name:          b.Meta.PackageAs,
+			Version:       name,
+			DisplayName:   fmt.Sprintf("%s %s", b.Meta.PackageAs, name),
+			ReleaseType:   releaseType,
+			Changelog:     notes,
+			ChangelogType: changelogType,
+			GameVersions:  gameVersions,
+			Interfaces:    interfaces,
+			Commit:        b.Revision.Hash,
+			SHA256:        hex.EncodeToString(sum[:]),
+		},
+	}, nil
+}
+
+// setting returns the first non empty value of a flag, an environment
+// variable and the user config.
+func setting(flag, env, config string) string {
+	if flag != "" {
+		return flag
+	}
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	return config
+}
+
+func newPublishCmd() *cobra.Command {
+	var (
+		endpoint string
+		token    string
+		retries  int
+		timeout  time.Duration
+		untagged bool
+		dryRun   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Upload the release to an addon distribution API",
+		Long: `Builds the package of the tagged HEAD, exactly like package does, and uploads it with a
+multipart POST to the publish endpoint. The "metadata" part is JSON holding the version, the
+release type (release, beta or alpha, from the pre-release part of the version), the
+changelog, the game versions derived from ## Interface: in Moonlight.toc, the commit and the
+SHA-256 of the zip. The "file" part is the zip. The request is authenticated with
+"Authorization: Bearer <token>".
+
+The endpoint and token are taken from --endpoint and --token, the MOONLIGHT_PUBLISH_ENDPOINT
+and MOONLIGHT_PUBLISH_TOKEN environment variables, or publishEndpoint and publishToken in the
+user config file, in that order.
+
+Uploads that can not connect, or that the server answers with 429 or 503, are retried with
+exponential backoff. Other failures are not retried, as the upload may have been accepted.
+The command refuses to run when the worktree has uncommitted changes, or when HEAD is not
+tagged with a version unless --untagged is given, which publishes an alpha versioned with the
+git describe string of HEAD. Use --dry-run to print the request instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			cfg, err := util.LoadUserConfig()
+			if err != nil {
+				return err
+			}
+			endpoint = setting(endpoint, EndpointEnv, cfg.PublishEndpoint)
+			token = setting(token, TokenEnv, cfg.PublishToken)
+			if endpoint == "" {
+				return fmt.Errorf("no publish endpoint is configured, pass --endpoint or set %s", EndpointEnv)
+			}
+			if token == "" && !dryRun {
+				return fmt.Errorf("no publish token is configured, set %s or pass --token", TokenEnv)
+			}
+
+			upload, err := NewUpload(root, endpoint, token, untagged, dryRun, func(msg string) {
+				fmt.Printf("warning: %s\n", msg)
+			})
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				fmt.Println("Would send this request:")
+				fmt.Println()
+				return upload.Describe(os.Stdout)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			client := &http.Client{Timeout: timeout}
+			resp, err := Publish(ctx, client, upload, retries, func(msg string) {
+				fmt.Println(msg)
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Published %s to %s\n", upload.Metadata.DisplayName, endpoint)
+			if msg := strings.TrimSpace(string(resp)); msg != "" {
+				fmt.Println(msg)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "URL to upload the release to")
+	cmd.Flags().StringVar(&token, "token", "", "API token, prefer "+TokenEnv+" to keep it out of the shell history")
+	cmd.Flags().IntVar(&retries, "retries", 3, "How many times to retry a failed upload")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Timeout of a single upload attempt")
+	cmd.Flags().BoolVar(&untagged, "untagged", false, "Publish an untagged HEAD as an alpha, versioned with git describe")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the request instead of sending it")
+
+	return cmd
+}
@@ -0,0 +1,166 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testUpload(endpoint string) *Upload {
+	return &Upload{
+		Endpoint: endpoint,
+		Token:    "secret",
+		Archive:  "Moonlight-v1.2.3.zip",
+		Zip:      []byte("PK zip"),
+		Metadata: Metadata{Name: "Moonlight", Version: "v1.2.3", ReleaseType: "release"},
+	}
+}
+
+// server starts a test server that answers with the statuses in order,
+// the last one for every request after them, and counts the requests.
+func server(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var count atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(count.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &count
+}
+
+// logTo returns a Publish log that writes to the test log.
+func logTo(t *testing.T) func(string) {
+	return func(msg string) { t.Log(msg) }
+}
+
+func init() {
+	retryDelay = time.Millisecond
+}
+
+func TestPublishRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse the upload: %v", err)
+			return
+		}
+		var metadata Metadata
+		if err := json.Unmarshal([]byte(r.FormValue("metadata")), &metadata); err != nil {
+			t.Errorf("failed to decode the metadata: %v", err)
+		}
+		if metadata.Version != "v1.2.3" {
+			t.Errorf("metadata version = %q, want v1.2.3", metadata.Version)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("upload has no file: %v", err)
+			return
+		}
+		zip, _ := io.ReadAll(file)
+		if header.Filename != "Moonlight-v1.2.3.zip" || string(zip) != "PK zip" {
+			t.Errorf("file = %s %q, want the archive", header.Filename, zip)
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id": 1}`)
+	}))
+	defer srv.Close()
+
+	body, err := Publish(context.Background(), srv.Client(), testUpload(srv.URL), 3, logTo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id": 1}` {
+		t.Errorf("response = %q, want the server response", body)
+	}
+
+	u := testUpload(srv.URL)
+	u.Token = "wrong"
+	_, err = Publish(context.Background(), srv.Client(), u, 3, logTo(t))
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized: bad token") {
+		t.Errorf("error = %v, want the 401 answer", err)
+	}
+}
+
+func TestPublishRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int32
+		wantErr  string
+	}{
+		{"success", []int{200}, 3, 1, ""},
+		{"unavailable then success", []int{503, 503, 200}, 3, 3, ""},
+		{"rate limited then success", []int{429, 201}, 3, 2, ""},
+		{"out of retries", []int{503}, 2, 3, "503 Service Unavailable"},
+		{"client error is final", []int{400}, 3, 1, "400 Bad Request"},
+		{"server error is final", []int{500}, 3, 1, "500 Internal Server Error"},
+		{"gateway timeout is final", []int{504}, 3, 1, "504 Gateway Timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, count := server(t, tt.statuses...)
+			_, err := Publish(context.Background(), srv.Client(), testUpload(srv.URL), tt.retries, logTo(t))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			if got := count.Load(); got != tt.requests {
+				t.Errorf("sent %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestPublishTransportErrors(t *testing.T) {
+	// The connection drops once the request was sent, so the upload may
+	// have been accepted and must not be sent again.
+	var count atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		io.Copy(io.Discard, r.Body)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+	_, err := Publish(context.Background(), srv.Client(), testUpload(srv.URL), 3, logTo(t))
+	if err == nil || !strings.Contains(err.Error(), "check the release") {
+		t.Errorf("error = %v, want a final upload error", err)
+	}
+	if got := count.Load(); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+
+	// Nothing listens, so the upload never reached a server and is retried.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	var retries int
+	_, err = Publish(context.Background(), http.DefaultClient, testUpload(closed.URL), 2, func(string) { retries++ })
+	if err == nil || strings.Contains(err.Error(), "check the release") {
+		t.Errorf("error = %v, want a connection error", err)
+	}
+	if retries != 2 {
+		t.Errorf("retried %d times, want 2", retries)
+	}
+}
@@ -0,0 +1,174 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changelog"
+	"github.com/Cidan/Moonlight/tools/moonlight/semver"
+	"github.com/Cidan/Moonlight/tools/moonlight/toc"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/Cidan/Moonlight/tools/moonlight/vcs"
+	"github.com/spf13/cobra"
+)
+
+// DefaultLint is the command that lints the addon, using the diagnostics
+// configured in .emmyrc.json.
+const DefaultLint = "emmylua_check ."
+
+// NextVersion computes the version to tag from the existing tags. bump is
+// major, minor, patch or an explicit version, which must be higher than
+// every existing version and not be tagged yet.
+func NextVersion(tags []string, bump string) (semver.Version, error) {
+	latest := semver.Version{Prefix: "v"}
+	found := false
+	for _, tag := range tags {
+		if v, ok := semver.Parse(tag); ok && (!found || v.Compare(latest) > 0) {
+			latest, found = v, true
+		}
+	}
+
+	switch bump {
+	case "major", "minor", "patch":
+		return latest.Bump(bump)
+	}
+	next, ok := semver.Parse(bump)
+	if !ok {
+		return semver.Version{}, fmt.Errorf("%q is not major, minor, patch or a semantic version such as v1.2.3", bump)
+	}
+	if slices.Contains(tags, next.String()) {
+		return semver.Version{}, fmt.Errorf("tag %s already exists", next)
+	}
+	if found && next.Compare(latest) <= 0 {
+		return semver.Version{}, fmt.Errorf("%s is not higher than the latest version %s", next, latest)
+	}
+	return next, nil
+}
+
+// checkToc runs toc check and returns an error listing every problem.
+func checkToc(root string) error {
+	f, err := toc.Load(filepath.Join(root, "Moonlight.toc"))
+	if err != nil {
+		return err
+	}
+	problems, err := toc.Check(root, f)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, p := range problems {
+		if p.Severity == toc.SeverityError {
+			errs = append(errs, "  "+p.String())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("toc check failed:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// lint runs the lint command in root and returns an error with its output
+// if it fails.
+func lint(root, command string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("the lint command is empty")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("lint command %s not found, install it or set --lint", args[0])
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = root
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("lint failed: %w\n%s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+func newTagCmd() *cobra.Command {
+	var (
+		dryRun      bool
+		lintCommand string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tag [major|minor|patch|<version>]",
+		Short: "Create an annotated tag for the next release",
+		Long: `Computes the next semantic version from the existing tags and creates an annotated
+tag for it on HEAD, with the generated changelog as the tag message. The argument is the
+part of the version to bump, patch by default, or an explicit version such as v1.2.0.
+
+The command refuses to run when the worktree has uncommitted changes, when HEAD is already
+tagged with a version, or when toc check or the lint command fail. Use --dry-run to run
+every check and print the tag without creating it.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bump := "patch"
+			if len(args) == 1 {
+				bump = args[0]
+			}
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+
+			tags, headTags, err := vcs.TagNames(root)
+			if err != nil {
+				return err
+			}
+			for _, tag := range headTags {
+				if _, ok := semver.Parse(tag); ok {
+					return fmt.Errorf("HEAD is already tagged %s", tag)
+				}
+			}
+			next, err := NextVersion(tags, bump)
+			if err != nil {
+				return err
+			}
+
+			dirty, err := vcs.Dirty(root)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("the worktree has uncommitted changes, commit or stash them before tagging")
+			}
+			if err := checkToc(root); err != nil {
+				return err
+			}
+			fmt.Println("toc check passed")
+			if err := lint(root, lintCommand); err != nil {
+				return err
+			}
+			fmt.Println("lint passed")
+
+			c, err := changelog.Generate(root, "", next.String())
+			if err != nil {
+				return err
+			}
+			message := string(c.Markdown())
+
+			if dryRun {
+				fmt.Printf("Would create tag %s with this message:\n\n%s", next, message)
+				return nil
+			}
+			if err := vcs.CreateTag(root, next.String(), message); err != nil {
+				return err
+			}
+			fmt.Printf("Created tag %s, push it with: git push origin %s\n", next, next)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Run the checks and print the tag instead of creating it")
+	cmd.Flags().StringVar(&lintCommand, "lint", DefaultLint, "Command that lints the addon, run from the repo root")
+
+	return cmd
+}
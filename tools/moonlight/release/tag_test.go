@@ -0,0 +1,48 @@
+package release
+
+import "testing"
+
+func TestNextVersion(t *testing.T) {
+	tags := []string{"v1.1.0", "v1.2.3", "v1.2.0", "nightly", "v1.3.0-beta"}
+	tests := []struct {
+		name string
+		tags []string
+		bump string
+		// want is the version, or empty when NextVersion fails.
+		want string
+	}{
+		{"first patch", nil, "patch", "v0.0.1"},
+		{"first minor", nil, "minor", "v0.1.0"},
+		{"first explicit", nil, "v1.0.0", "v1.0.0"},
+		{"release a pre-release", tags, "patch", "v1.3.0"},
+		{"minor of a pre-release", tags, "minor", "v1.3.0"},
+		{"major of a pre-release", tags, "major", "v2.0.0"},
+		{"patch", []string{"v1.2.3", "v1.10.0", "v1.9.0"}, "patch", "v1.10.1"},
+		{"without prefix", []string{"1.2.3"}, "minor", "1.3.0"},
+		{"explicit", tags, "v1.4.0", "v1.4.0"},
+		{"explicit release of the pre-release", tags, "v1.3.0", "v1.3.0"},
+		{"explicit pre-release", tags, "v1.3.0-rc.1", "v1.3.0-rc.1"},
+		{"explicit below the latest", tags, "v1.2.4", ""},
+		{"explicit pre-release below the latest", tags, "v1.3.0-alpha", ""},
+		{"explicit equal to the latest", tags, "1.3.0-beta", ""},
+		{"explicit already tagged", tags, "v1.3.0-beta", ""},
+		{"not a version", tags, "next", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextVersion(tt.tags, tt.bump)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("NextVersion(%q) = %s, want an error", tt.bump, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("NextVersion(%q) = %s, want %s", tt.bump, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package scan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Accessor is a Moonlight:GetX() function declared in boot/boot.lua.
+type Accessor struct {
+	Name string `json:"name"`
+	// Class is the class name returned from self.classes, if any.
+	Class string `json:"class"`
+	// Return is the type in the ---@return annotation, if any.
+	Return string `json:"return"`
+	Line   int    `json:"line"`
+}
+
+var (
+	reAccessorHeader = regexp.MustCompile(`^function\s+Moonlight:(Get\w+)\s*\(\s*\)`)
+	reAccessorReturn = regexp.MustCompile(`^\s*return\s+self\.classes\.([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+	reReturnAnno     = regexp.MustCompile(`^---@return\s+(\S+)`)
+)
+
+// Accessors returns every Moonlight:GetX() accessor in the boot file source,
+// in source order. It lives here rather than in the boot package so that
+// every package that resolves accessors to classes shares one parser.
+func Accessors(src []byte) []Accessor {
+	l := strings.Split(string(src), "\n")
+	var accessors []Accessor
+	for i, line := range l {
+		match := reAccessorHeader.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		a := Accessor{Name: match[1], Line: i + 1}
+		for j := i - 1; j >= 0 && strings.HasPrefix(strings.TrimSpace(l[j]), "---"); j-- {
+			if m := reReturnAnno.FindStringSubmatch(strings.TrimSpace(l[j])); m != nil {
+				a.Return = m[1]
+			}
+		}
+		for j := i + 1; j < len(l) && strings.TrimRight(l[j], " \t\r") != "end"; j++ {
+			if m := reAccessorReturn.FindStringSubmatch(l[j]); m != nil {
+				a.Class = m[1]
+			}
+		}
+		accessors = append(accessors, a)
+	}
+	return accessors
+}
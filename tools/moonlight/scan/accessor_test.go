@@ -0,0 +1,32 @@
+package scan
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAccessors(t *testing.T) {
+	src := `---@return Bag
+function Moonlight:GetBag()
+  return self.classes.bag
+end
+
+function Moonlight:GetLoader()
+  ---@type Loader
+  local loader = self.classes.loader
+  return loader
+end
+
+function Moonlight:Start()
+end
+
+local function Moonlight:GetHidden() end
+`
+	want := []Accessor{
+		{Name: "GetBag", Class: "bag", Return: "Bag", Line: 2},
+		{Name: "GetLoader", Line: 6},
+	}
+	if got := Accessors([]byte(src)); !slices.Equal(got, want) {
+		t.Errorf("Accessors() = %+v, want %+v", got, want)
+	}
+}
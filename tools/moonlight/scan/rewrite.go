@@ -0,0 +1,65 @@
+package scan
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/lua"
+)
+
+// Rewriter holds the callbacks used by Rewrite. Each callback receives the
+// exact source text of a token and returns its replacement. A nil callback
+// leaves those tokens untouched.
+type Rewriter struct {
+	// Ident is called for every identifier. member is true when the
+	// identifier follows a '.' or ':' index operator.
+	Ident func(name string, member bool) string
+	// String is called for every string literal, including its quotes.
+	String func(lit string) string
+	// Comment is called for every comment, including its leading dashes.
+	Comment func(text string) string
+}
+
+// Rewrite splits Lua source into tokens and applies the rewriter callbacks.
+// Everything that is not an identifier, string or comment is copied through
+// unchanged. It fails if the source can not be tokenized.
+func Rewrite(src string, r Rewriter) (string, error) {
+	toks, comments, err := lua.Tokenize([]byte(src))
+	if err != nil {
+		return "", err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for i, tok := range toks {
+		start, end := tok.Pos.Offset, tok.End.Offset
+		switch {
+		case tok.Kind == lua.Name && r.Ident != nil:
+			member := i > 0 && toks[i-1].Kind == lua.Symbol && (toks[i-1].Text == "." || toks[i-1].Text == ":")
+			edits = append(edits, edit{start, end, r.Ident(tok.Text, member)})
+		case tok.Kind == lua.String && r.String != nil:
+			edits = append(edits, edit{start, end, r.String(src[start:end])})
+		}
+	}
+	if r.Comment != nil {
+		for _, c := range comments {
+			start, end := c.Pos.Offset, c.End.Offset
+			edits = append(edits, edit{start, end, r.Comment(src[start:end])})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var out strings.Builder
+	out.Grow(len(src))
+	prev := 0
+	for _, e := range edits {
+		out.WriteString(src[prev:e.start])
+		out.WriteString(e.text)
+		prev = e.end
+	}
+	out.WriteString(src[prev:])
+	return out.String(), nil
+}
@@ -0,0 +1,50 @@
+package scan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	src := `local bag = moonlight:NewClass("bag") -- bag
+---@class bag
+local x = { bag = bag.bag, [ [[bag]] ] = 'bag' }
+function bag.bag.sub:bag(...) return x..bag end
+`
+	got, err := Rewrite(src, Rewriter{
+		Ident: func(name string, member bool) string {
+			if name != "bag" {
+				return name
+			}
+			if member {
+				return "MEMBER"
+			}
+			return "BAG"
+		},
+		String: func(lit string) string {
+			return strings.ToUpper(lit)
+		},
+		Comment: func(text string) string {
+			return "--[" + text + "]"
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `local BAG = moonlight:NewClass("BAG") --[-- bag]
+--[---@class bag]
+local x = { BAG = BAG.MEMBER, [ [[BAG]] ] = 'BAG' }
+function BAG.MEMBER.sub:MEMBER(...) return x..BAG end
+`
+	if got != want {
+		t.Errorf("Rewrite() =\n%s\nwant\n%s", got, want)
+	}
+
+	unchanged, err := Rewrite(src, Rewriter{})
+	if err != nil || unchanged != src {
+		t.Errorf("Rewrite() without callbacks = %q, %v, want the source", unchanged, err)
+	}
+	if _, err := Rewrite("x = 'unfinished", Rewriter{}); err == nil {
+		t.Error("Rewrite() of an unfinished string succeeded, want an error")
+	}
+}
@@ -0,0 +1,138 @@
+package scan
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// skipDirs are repo directories that never contain addon Lua source.
+var skipDirs = map[string]bool{
+	".git":        true,
+	".roo":        true,
+	".vscode":     true,
+	"annotations": true,
+	"tools":       true,
+}
+
+var reNewClass = regexp.MustCompile(`moonlight:NewClass\(\s*"([^"]+)"\s*\)`)
+
+// CallSite is a single line of Lua source that calls a moonlight accessor.
+type CallSite struct {
+	// Path is the repo relative, forward slash path of the file.
+	Path string
+	Line int
+	Text string
+}
+
+func (c CallSite) String() string {
+	return fmt.Sprintf("%s:%d: %s", c.Path, c.Line, strings.TrimSpace(c.Text))
+}
+
+// LuaFiles returns the repo relative, forward slash paths of every addon
+// Lua file under root, sorted.
+func LuaFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".lua") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk lua files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Classes returns every class name registered via moonlight:NewClass in
+// the given Lua source, in source order.
+func Classes(content []byte) []string {
+	var classes []string
+	for _, match := range reNewClass.FindAllSubmatch(content, -1) {
+		classes = append(classes, string(match[1]))
+	}
+	return classes
+}
+
+// AccessorCalls finds every call to moonlight:<accessor>() in the addon Lua
+// files under root. Files listed in exclude, by repo relative path, are skipped.
+func AccessorCalls(root, accessor string, exclude ...string) ([]CallSite, error) {
+	files, err := LuaFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]bool)
+	for _, path := range exclude {
+		skip[filepath.ToSlash(path)] = true
+	}
+
+	reCall := regexp.MustCompile(`\bmoonlight:` + regexp.QuoteMeta(accessor) + `\s*\(`)
+	var sites []CallSite
+	for _, rel := range files {
+		if skip[rel] {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			if reCall.MatchString(line) {
+				sites = append(sites, CallSite{Path: rel, Line: i + 1, Text: line})
+			}
+		}
+	}
+	return sites, nil
+}
+
+// Registration is a single moonlight:NewClass call.
+type Registration struct {
+	Class string `json:"class"`
+	// Path is the repo relative, forward slash path of the file.
+	Path string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Registrations finds every moonlight:NewClass call in the addon Lua files
+// under root, ordered by file and line.
+func Registrations(root string) ([]Registration, error) {
+	files, err := LuaFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var regs []Registration
+	for _, rel := range files {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			for _, class := range Classes([]byte(line)) {
+				regs = append(regs, Registration{Class: class, Path: rel, Line: i + 1})
+			}
+		}
+	}
+	return regs, nil
+}
@@ -0,0 +1,81 @@
+package scan
+
+import (
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/lua"
+)
+
+// ScopeCall is a call to a moonlight accessor that runs when its file is
+// loaded, i.e. one that is not inside a function body.
+type ScopeCall struct {
+	Accessor string
+	Line     int
+}
+
+// FileScopeCalls returns every moonlight:GetX() call in the Lua source that
+// runs at file scope, in source order. Calls inside function bodies only run
+// later and are not returned, but calls inside top level do, if and loop
+// blocks are. It fails if the source can not be tokenized.
+func FileScopeCalls(src []byte) ([]ScopeCall, error) {
+	toks, _, err := lua.Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	type block struct{ function bool }
+	var (
+		calls     []ScopeCall
+		stack     []block
+		functions int
+		loops     int
+	)
+	pop := func() {
+		if len(stack) == 0 {
+			return
+		}
+		if stack[len(stack)-1].function {
+			functions--
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	for i, tok := range toks {
+		if tok.Kind == lua.Name {
+			if tok.Text != "moonlight" || functions > 0 || i+3 >= len(toks) {
+				continue
+			}
+			colon, name, paren := toks[i+1], toks[i+2], toks[i+3]
+			if isSymbol(colon, ":") && name.Kind == lua.Name && strings.HasPrefix(name.Text, "Get") && isSymbol(paren, "(") {
+				calls = append(calls, ScopeCall{Accessor: name.Text, Line: name.Pos.Line})
+			}
+			continue
+		}
+		if tok.Kind != lua.Keyword {
+			continue
+		}
+		switch tok.Text {
+		case "function":
+			stack = append(stack, block{function: true})
+			functions++
+		case "if", "repeat":
+			stack = append(stack, block{})
+		case "while", "for":
+			// The loop body is opened by the following do.
+			stack = append(stack, block{})
+			loops++
+		case "do":
+			if loops > 0 {
+				loops--
+			} else {
+				stack = append(stack, block{})
+			}
+		case "end", "until":
+			pop()
+		}
+	}
+	return calls, nil
+}
+
+func isSymbol(tok lua.Token, text string) bool {
+	return tok.Kind == lua.Symbol && tok.Text == text
+}
@@ -0,0 +1,42 @@
+package scan
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestFileScopeCalls(t *testing.T) {
+	src := `local a = moonlight:GetA()
+if x then
+  local b = moonlight:GetB()
+end
+for i = 1, 2 do moonlight:GetC() end
+local function f()
+  moonlight:GetD()
+end
+function bag:New()
+  return moonlight:GetE()
+end
+local t = { handler = function() moonlight:GetF() end, g = moonlight:GetG() }
+-- moonlight:GetH()
+local s = "moonlight:GetI()"
+moonlight:NewClass("x")
+other:GetJ()
+while x do repeat moonlight:GetK() until y end
+`
+	calls, err := FileScopeCalls([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range calls {
+		got = append(got, fmt.Sprintf("%s:%d", c.Accessor, c.Line))
+	}
+	if want := []string{"GetA:1", "GetB:3", "GetC:5", "GetG:12", "GetK:17"}; !slices.Equal(got, want) {
+		t.Errorf("FileScopeCalls() = %q, want %q", got, want)
+	}
+	if _, err := FileScopeCalls([]byte("x = [[unfinished")); err == nil {
+		t.Error("FileScopeCalls() of an unfinished string succeeded, want an error")
+	}
+}
@@ -0,0 +1,123 @@
+// Package semver parses and compares the semantic versions of release
+// tags.
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reSemver = regexp.MustCompile(`^(v?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?$`)
+
+// Version is a semantic version, as used for release tags.
+type Version struct {
+	// Prefix is "v" for tags such as v1.2.3, or empty.
+	Prefix              string
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// Parse parses a version such as 1.2.3, v1.2.3 or v1.2.3-beta.1.
+func Parse(s string) (Version, bool) {
+	match := reSemver.FindStringSubmatch(s)
+	if match == nil {
+		return Version{}, false
+	}
+	v := Version{Prefix: match[1], Pre: match[5]}
+	v.Major, _ = strconv.Atoi(match[2])
+	v.Minor, _ = strconv.Atoi(match[3])
+	v.Patch, _ = strconv.Atoi(match[4])
+	return v, true
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or higher than
+// o. Prefixes are ignored, and pre-releases sort before their release.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	default:
+		return comparePre(v.Pre, o.Pre)
+	}
+}
+
+// Bump returns the next major, minor or patch version. Bumping a
+// pre-release whose lower parts are zero releases it, e.g. a patch bump of
+// 1.3.0-beta gives 1.3.0.
+func (v Version) Bump(part string) (Version, error) {
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	pre := v.Pre != ""
+	switch part {
+	case "major":
+		if !pre || v.Minor != 0 || v.Patch != 0 {
+			next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+		}
+	case "minor":
+		if !pre || v.Patch != 0 {
+			next.Minor, next.Patch = v.Minor+1, 0
+		}
+	case "patch":
+		if !pre {
+			next.Patch = v.Patch + 1
+		}
+	default:
+		return Version{}, fmt.Errorf("unknown version part %q, expected major, minor or patch", part)
+	}
+	return next, nil
+}
+
+// comparePre compares dot separated pre-release identifiers as described by
+// semver: numeric identifiers compare as numbers and sort before others.
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case as[i] != bs[i]:
+			if as[i] < bs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(d int) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,88 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+		ok   bool
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, true},
+		{"v1.2.3", Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3}, true},
+		{"v1.3.0-beta.1", Version{Prefix: "v", Major: 1, Minor: 3, Pre: "beta.1"}, true},
+		{"v01.2.3", Version{}, false},
+		{"v1.2", Version{}, false},
+		{"release-1", Version{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+		if ok && got.String() != tt.in {
+			t.Errorf("Parse(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.2.3", "v1.2.4", -1},
+		{"v1.10.0", "v1.9.9", 1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.3.0-beta", "v1.3.0", -1},
+		{"v1.3.0", "v1.2.9-beta", 1},
+		{"v1.3.0-alpha", "v1.3.0-beta", -1},
+		{"v1.3.0-beta.2", "v1.3.0-beta.10", -1},
+		{"v1.3.0-beta.1", "v1.3.0-beta.rc", -1},
+		{"v1.3.0-beta", "v1.3.0-beta.1", -1},
+		{"v1.3.0-rc.1", "v1.3.0-rc.1", 0},
+	}
+	for _, tt := range tests {
+		a, _ := Parse(tt.a)
+		b, _ := Parse(tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.Compare(a); got != -tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		v, part, want string
+	}{
+		{"v1.2.3", "patch", "v1.2.4"},
+		{"v1.2.3", "minor", "v1.3.0"},
+		{"v1.2.3", "major", "v2.0.0"},
+		{"1.2.3", "patch", "1.2.4"},
+		{"v1.3.0-beta", "patch", "v1.3.0"},
+		{"v1.3.0-beta", "minor", "v1.3.0"},
+		{"v1.3.0-beta", "major", "v2.0.0"},
+		{"v2.0.0-rc.1", "major", "v2.0.0"},
+		{"v1.2.4-beta", "patch", "v1.2.4"},
+		{"v1.2.4-beta", "minor", "v1.3.0"},
+		{"v1.2.4-beta", "major", "v2.0.0"},
+	}
+	for _, tt := range tests {
+		v, _ := Parse(tt.v)
+		got, err := v.Bump(tt.part)
+		if err != nil {
+			t.Errorf("%s.Bump(%q) failed: %v", tt.v, tt.part, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s.Bump(%q) = %s, want %s", tt.v, tt.part, got, tt.want)
+		}
+	}
+	if _, err := (Version{}).Bump("build"); err == nil {
+		t.Error(`Bump("build") succeeded, want an error`)
+	}
+}
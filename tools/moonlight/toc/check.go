@@ -0,0 +1,231 @@
+package toc
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+)
+
+// Severity is how serious a Problem is. Only errors fail a check.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a single issue found by Check.
+type Problem struct {
+	Severity Severity
+	// Line is the 1-based TOC line the problem refers to, or 0.
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s: Moonlight.toc:%d: %s", p.Severity, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+}
+
+// Check validates the TOC against the repo at root: every loaded file must
+// exist with exactly matching case, no entry may be listed twice, the
+// pinned Head and Tail entries must be in place, and every Lua file that
+// registers a class must be loaded before any file that uses it at file
+// scope.
+func Check(root string, f *File) ([]Problem, error) {
+	var problems []Problem
+	problems = append(problems, f.checkEntries(root)...)
+	problems = append(problems, f.checkOrder()...)
+
+	refs, err := f.checkReferences(root)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, refs...)
+
+	loaded, err := f.checkLoaded(root)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, loaded...)
+
+	deps, err := f.checkDependencies(root)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, deps...)
+	return problems, nil
+}
+
+// checkEntries reports duplicate entries and entries whose file is missing
+// or differs in case from the file on disk.
+func (f *File) checkEntries(root string) []Problem {
+	var problems []Problem
+	seen := make(map[string]int)
+	cache := make(map[string][]os.DirEntry)
+	for i, line := range f.Lines {
+		if line.Kind != Entry {
+			continue
+		}
+		key := strings.ToLower(line.Path)
+		if first, ok := seen[key]; ok {
+			problems = append(problems, Problem{SeverityError, i + 1, fmt.Sprintf("%s is already listed on line %d", line.Path, first)})
+			continue
+		}
+		seen[key] = i + 1
+
+		ext := strings.ToLower(path.Ext(line.Path))
+		if ext != ".lua" && ext != ".xml" {
+			problems = append(problems, Problem{SeverityWarning, i + 1, fmt.Sprintf("%s is not a .lua or .xml file", line.Path)})
+		}
+		if p, ok := checkExists(root, line.Path, cache); !ok {
+			p.Line = i + 1
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// checkReferences reports files that an XML file loads, through a Script or
+// Include element, that are missing or differ in case from the file on
+// disk. The problem is reported on the TOC entry that loads the XML file.
+func (f *File) checkReferences(root string) ([]Problem, error) {
+	loads, err := f.Loads(root)
+	if err != nil {
+		return nil, err
+	}
+	var problems []Problem
+	cache := make(map[string][]os.DirEntry)
+	for _, l := range loads {
+		if l.Via == "" {
+			continue
+		}
+		if p, ok := checkExists(root, l.Path, cache); !ok {
+			p.Line = f.IndexOf(l.Entry) + 1
+			p.Message += fmt.Sprintf(", it is loaded through %s", l.Via)
+			problems = append(problems, p)
+		}
+	}
+	return problems, nil
+}
+
+// checkExists reports a problem if rel does not exist under root with
+// exactly matching case. The client on Windows ignores case, but on macOS
+// and Linux under Wine a mismatch fails to load.
+func checkExists(root, rel string, cache map[string][]os.DirEntry) (Problem, bool) {
+	dir := root
+	var actual []string
+	for _, segment := range strings.Split(rel, "/") {
+		entries, ok := cache[dir]
+		if !ok {
+			entries, _ = os.ReadDir(dir)
+			cache[dir] = entries
+		}
+		match := ""
+		for _, e := range entries {
+			if e.Name() == segment {
+				match = segment
+				break
+			}
+			if strings.EqualFold(e.Name(), segment) {
+				match = e.Name()
+			}
+		}
+		if match == "" {
+			return Problem{Severity: SeverityError, Message: fmt.Sprintf("%s does not exist", rel)}, false
+		}
+		actual = append(actual, match)
+		dir = filepath.Join(dir, match)
+	}
+	if onDisk := strings.Join(actual, "/"); onDisk != rel {
+		return Problem{Severity: SeverityError, Message: fmt.Sprintf("%s does not match the case of %s on disk", rel, onDisk)}, false
+	}
+	return Problem{}, true
+}
+
+// checkOrder reports pinned Head entries that are missing or out of place,
+// and Tail entries that are not last.
+func (f *File) checkOrder() []Problem {
+	var problems []Problem
+	entries := f.Entries()
+	for i, want := range Head {
+		line := f.IndexOf(want) + 1
+		switch {
+		case line == 0:
+			problems = append(problems, Problem{SeverityError, 0, fmt.Sprintf("%s must be listed as entry %d", want, i+1)})
+		case i >= len(entries) || entries[i] != want:
+			problems = append(problems, Problem{SeverityError, line, fmt.Sprintf("%s must be entry %d, the load order must start with %s", want, i+1, strings.Join(Head, ", "))})
+		}
+	}
+	for i, want := range Tail {
+		line := f.IndexOf(want) + 1
+		pos := len(entries) - len(Tail) + i
+		switch {
+		case line == 0:
+			problems = append(problems, Problem{SeverityError, 0, fmt.Sprintf("%s must be listed last", want)})
+		case pos < 0 || entries[pos] != want:
+			problems = append(problems, Problem{SeverityError, line, fmt.Sprintf("%s must be the last entry", want)})
+		}
+	}
+	return problems
+}
+
+// checkDependencies reports every file scope moonlight:GetX() call that
+// runs before the file registering the class is loaded.
+func (f *File) checkDependencies(root string) ([]Problem, error) {
+	g, err := LoadGraph(root, f)
+	if err != nil {
+		return nil, err
+	}
+	entryOf := g.entryOf()
+	var problems []Problem
+	for _, d := range g.Violations() {
+		line := f.IndexOf(entryOf[d.Path]) + 1
+		problems = append(problems, Problem{SeverityError, line, fmt.Sprintf("%s, but %s loads later, run toc sort", d, d.Provider)})
+	}
+	return problems, nil
+}
+
+// checkLoaded reports Lua files that register a class but are never loaded,
+// and Lua files that are only loaded through an XML file instead of being
+// listed in the TOC.
+func (f *File) checkLoaded(root string) ([]Problem, error) {
+	loads, err := f.Loads(root)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]LoadedFile)
+	for _, l := range loads {
+		loaded[strings.ToLower(l.Path)] = l
+	}
+
+	files, err := scan.LuaFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	var problems []Problem
+	for _, rel := range files {
+		l, ok := loaded[strings.ToLower(rel)]
+		if ok && l.Via == "" {
+			continue
+		}
+		if ok {
+			problems = append(problems, Problem{Severity: SeverityWarning, Message: fmt.Sprintf("%s is not listed in Moonlight.toc, it is only loaded through %s", rel, l.Via)})
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		if classes := scan.Classes(content); len(classes) > 0 {
+			problems = append(problems, Problem{Severity: SeverityError, Message: fmt.Sprintf("%s registers class %q but is never loaded, add it to Moonlight.toc", rel, classes[0])})
+		}
+	}
+	return problems, nil
+}
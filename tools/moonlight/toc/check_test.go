@@ -0,0 +1,66 @@
+package toc
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeRepo writes files, by slash separated relative path, into a new
+// temp dir and returns it.
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCheckXMLReferences(t *testing.T) {
+	root := writeRepo(t, map[string]string{
+		"boot/boot.lua":       "",
+		"pool/pool.lua":       "",
+		"constants/const.lua": "",
+		"boot/init.lua":       "",
+		"frames/frames.xml": `<Ui>
+	<Script file="frame.lua"/>
+	<Script file="Missing.lua"/>
+	<Include file="nested\nested.xml"/>
+</Ui>`,
+		"frames/frame.lua":         "",
+		"frames/nested/nested.xml": `<Ui><Script file="Deep.lua"/></Ui>`,
+		"frames/nested/deep.lua":   "",
+	})
+	f := Parse([]byte(`## Interface: 110200
+boot/boot.lua
+pool/pool.lua
+constants/const.lua
+frames/frames.xml
+boot/init.lua
+`))
+	problems, err := Check(root, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			got = append(got, p.String())
+		}
+	}
+	want := []string{
+		"error: Moonlight.toc:5: frames/Missing.lua does not exist, it is loaded through frames/frames.xml",
+		"error: Moonlight.toc:5: frames/nested/Deep.lua does not match the case of frames/nested/deep.lua on disk, it is loaded through frames/nested/nested.xml",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Check() errors =\n%q\nwant\n%q", got, want)
+	}
+}
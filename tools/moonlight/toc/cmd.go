@@ -0,0 +1,134 @@
+package toc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/changeset"
+	"github.com/Cidan/Moonlight/tools/moonlight/util"
+	"github.com/spf13/cobra"
+)
+
+// ErrCheckFailed is returned by toc check when it finds an error.
+var ErrCheckFailed = errors.New("Moonlight.toc check failed")
+
+// NewTocCmd creates and returns the toc command with its subcommands.
+func NewTocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "toc",
+		Short: "Manage Moonlight.toc",
+	}
+
+	cmd.AddCommand(newCheckCmd())
+	cmd.AddCommand(newSortCmd())
+
+	return cmd
+}
+
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Validate Moonlight.toc",
+		Long: `Validates Moonlight.toc against the repository:
+  - every listed .lua and .xml file, and every file they load, exists with exactly matching case
+  - no file is listed twice
+  - boot/boot.lua, pool/pool.lua and constants/const.lua come first, and boot/init.lua comes last
+  - every Lua file that calls moonlight:NewClass is loaded
+  - every moonlight:GetX() call at file scope runs after the file registering the class is loaded
+Lua files that are only loaded through an XML file are reported as warnings.
+The command exits with a non-zero status when any error is found.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			f, err := Load(filepath.Join(root, "Moonlight.toc"))
+			if err != nil {
+				return err
+			}
+
+			problems, err := Check(root, f)
+			if err != nil {
+				return err
+			}
+
+			numErrors := 0
+			for _, p := range problems {
+				fmt.Println(p)
+				if p.Severity == SeverityError {
+					numErrors++
+				}
+			}
+			fmt.Printf("%d entries checked, %d errors, %d warnings\n", len(f.Entries()), numErrors, len(problems)-numErrors)
+			if numErrors > 0 {
+				// The problems are the expected outcome of a check, not a
+				// misuse of the command.
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return ErrCheckFailed
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newSortCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "sort",
+		Short: "Order Moonlight.toc by file scope dependencies",
+		Long: `Builds a dependency graph from the moonlight:GetX() calls each file makes at file scope,
+and reorders Moonlight.toc so that every class is registered before it is used.
+boot/boot.lua, pool/pool.lua and constants/const.lua stay first and boot/init.lua stays last.
+Other entries keep their current relative order unless a dependency requires a move.
+Comments and blank lines keep their place. Dependency cycles are reported as errors.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := util.FindRepoRoot()
+			if err != nil {
+				return err
+			}
+			tocPath := filepath.Join(root, "Moonlight.toc")
+			f, err := Load(tocPath)
+			if err != nil {
+				return err
+			}
+
+			g, err := LoadGraph(root, f)
+			if err != nil {
+				return err
+			}
+			order, err := g.Sort(f)
+			if err != nil {
+				return err
+			}
+			if slices.Equal(order, f.Entries()) {
+				fmt.Println("Moonlight.toc is already in dependency order")
+				return nil
+			}
+			if err := f.Reorder(order); err != nil {
+				return err
+			}
+
+			cs := changeset.New(root)
+			cs.Write(tocPath, f.Bytes(), 0)
+			if err := cs.Commit(dryRun, os.Stdout); err != nil {
+				return fmt.Errorf("failed to write toc file: %w", err)
+			}
+			if !dryRun {
+				fmt.Println("Sorted Moonlight.toc")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the changes instead of applying them")
+
+	return cmd
+}
@@ -0,0 +1,125 @@
+package toc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/scan"
+)
+
+// Dependency is a file scope moonlight:GetX() call, which only works if the
+// file that registers the class was loaded first.
+type Dependency struct {
+	// Path is the file that makes the call.
+	Path     string
+	Line     int
+	Accessor string
+	Class    string
+	// Provider is the file that registers Class.
+	Provider string
+}
+
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s:%d calls moonlight:%s() at file scope", d.Path, d.Line, d.Accessor)
+}
+
+// Graph is the module dependency graph of a TOC.
+type Graph struct {
+	// Loads is every file the TOC loads, in load order.
+	Loads []LoadedFile
+	// Dependencies holds every file scope accessor call to a class that is
+	// registered by a loaded file, in load order.
+	Dependencies []Dependency
+}
+
+// LoadGraph builds the dependency graph for the TOC from the file scope
+// moonlight:GetX() calls in every loaded Lua file. Accessors are resolved
+// to classes through boot/boot.lua.
+func LoadGraph(root string, f *File) (*Graph, error) {
+	loads, err := f.Loads(root)
+	if err != nil {
+		return nil, err
+	}
+	bootSrc, err := os.ReadFile(filepath.Join(root, "boot", "boot.lua"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read boot file: %w", err)
+	}
+	accessors := make(map[string]string)
+	for _, a := range scan.Accessors(bootSrc) {
+		if a.Class != "" {
+			accessors[a.Name] = a.Class
+		}
+	}
+
+	providers := make(map[string]string)
+	calls := make(map[string][]scan.ScopeCall)
+	for _, l := range loads {
+		if !strings.EqualFold(filepath.Ext(l.Path), ".lua") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(l.Path)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", l.Path, err)
+		}
+		for _, class := range scan.Classes(content) {
+			if _, ok := providers[class]; !ok {
+				providers[class] = l.Path
+			}
+		}
+		if calls[l.Path], err = scan.FileScopeCalls(content); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", l.Path, err)
+		}
+	}
+
+	g := &Graph{Loads: loads}
+	for _, l := range loads {
+		for _, call := range calls[l.Path] {
+			class, ok := accessors[call.Accessor]
+			if !ok {
+				continue
+			}
+			provider, ok := providers[class]
+			if !ok || provider == l.Path {
+				continue
+			}
+			g.Dependencies = append(g.Dependencies, Dependency{
+				Path:     l.Path,
+				Line:     call.Line,
+				Accessor: call.Accessor,
+				Class:    class,
+				Provider: provider,
+			})
+		}
+	}
+	return g, nil
+}
+
+// entryOf maps every loaded file to the TOC entry that loads it.
+func (g *Graph) entryOf() map[string]string {
+	entries := make(map[string]string, len(g.Loads))
+	for _, l := range g.Loads {
+		entries[l.Path] = l.Entry
+	}
+	return entries
+}
+
+// Violations returns every dependency whose provider loads after the file
+// that uses it.
+func (g *Graph) Violations() []Dependency {
+	order := make(map[string]int, len(g.Loads))
+	for i, l := range g.Loads {
+		order[l.Path] = i
+	}
+	var violations []Dependency
+	for _, d := range g.Dependencies {
+		if order[d.Provider] > order[d.Path] {
+			violations = append(violations, d)
+		}
+	}
+	return violations
+}
@@ -0,0 +1,118 @@
+package toc
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// directiveIndex returns the line index of the "## key: value" directive,
+// or -1 if the file does not have it. Keys are matched without regard to
+// case, like the client does.
+func (f *File) directiveIndex(key string) int {
+	for i, line := range f.Lines {
+		if line.Kind != Directive {
+			continue
+		}
+		name, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line.Text), "##"), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Directive returns the value of the "## key: value" directive, and if the
+// file has it.
+func (f *File) Directive(key string) (string, bool) {
+	i := f.directiveIndex(key)
+	if i == -1 {
+		return "", false
+	}
+	_, value, _ := strings.Cut(f.Lines[i].Text, ":")
+	return strings.TrimSpace(value), true
+}
+
+// Interfaces returns the interface numbers of the "## Interface:"
+// directive, e.g. 110105, in the order they are listed.
+func (f *File) Interfaces() ([]int, error) {
+	value, ok := f.Directive("Interface")
+	if !ok {
+		return nil, fmt.Errorf("the toc file has no ## Interface: directive")
+	}
+	var interfaces []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 10000 {
+			return nil, fmt.Errorf("## Interface: %q is not an interface number such as 110105", strings.TrimSpace(field))
+		}
+		interfaces = append(interfaces, n)
+	}
+	return interfaces, nil
+}
+
+// GameVersion returns the game version of an interface number, e.g.
+// 11.1.5 for 110105.
+func GameVersion(iface int) string {
+	return fmt.Sprintf("%d.%d.%d", iface/10000, iface/100%100, iface%100)
+}
+
+// ParseGameVersion parses a game version such as 11.2.0, or 11.2 for its
+// first patch, or an interface number such as 110200, and returns the
+// interface number.
+func ParseGameVersion(version string) (int, error) {
+	if !strings.Contains(version, ".") {
+		n, err := strconv.Atoi(version)
+		if err != nil || n < 10000 {
+			return 0, fmt.Errorf("%q is not a game version such as 11.2.0 or an interface number such as 110200", version)
+		}
+		return n, nil
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%q is not a game version such as 11.2.0", version)
+	}
+	iface := 0
+	for i, scale := range []int{10000, 100, 1} {
+		if i >= len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 || (i > 0 && n > 99) {
+			return 0, fmt.Errorf("%q is not a game version such as 11.2.0", version)
+		}
+		iface += n * scale
+	}
+	if iface < 10000 {
+		return 0, fmt.Errorf("%q is not a game version such as 11.2.0", version)
+	}
+	return iface, nil
+}
+
+// Retail returns the highest interface number of the "## Interface:"
+// directive, which is the retail client when the file lists several.
+func (f *File) Retail() (int, error) {
+	interfaces, err := f.Interfaces()
+	if err != nil {
+		return 0, err
+	}
+	return slices.Max(interfaces), nil
+}
+
+// SetRetail replaces the highest interface number of the "## Interface:"
+// directive with iface, keeping the others.
+func (f *File) SetRetail(iface int) error {
+	interfaces, err := f.Interfaces()
+	if err != nil {
+		return err
+	}
+	i := slices.Index(interfaces, slices.Max(interfaces))
+	interfaces[i] = iface
+	values := make([]string, len(interfaces))
+	for i, n := range interfaces {
+		values[i] = strconv.Itoa(n)
+	}
+	f.Lines[f.directiveIndex("Interface")].Text = "## Interface: " + strings.Join(values, ", ")
+	return nil
+}
@@ -0,0 +1,63 @@
+package toc
+
+import (
+	"testing"
+)
+
+func TestParseGameVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+		wantErr bool
+	}{
+		{"11.2", 110200, false},
+		{"11.2.0", 110200, false},
+		{"11.1.5", 110105, false},
+		{"110200", 110200, false},
+		{"1.13.2", 11302, false},
+		{"1.2.3.4", 0, true},
+		{"11.100.0", 0, true},
+		{"11.-1.0", 0, true},
+		{"11..0", 0, true},
+		{"0.9", 0, true},
+		{"9999", 0, true},
+		{"latest", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := ParseGameVersion(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGameVersion() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseGameVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameVersion(t *testing.T) {
+	for iface, want := range map[int]string{110105: "11.1.5", 110200: "11.2.0", 11302: "1.13.2"} {
+		if got := GameVersion(iface); got != want {
+			t.Errorf("GameVersion(%d) = %s, want %s", iface, got, want)
+		}
+	}
+}
+
+func TestSetRetail(t *testing.T) {
+	f := Parse([]byte("## Title: Moonlight\n##interface: 11507, 110105 ,50500\nboot/boot.lua\n"))
+	if retail, err := f.Retail(); err != nil || retail != 110105 {
+		t.Fatalf("Retail() = %d, %v, want 110105", retail, err)
+	}
+	if err := f.SetRetail(110200); err != nil {
+		t.Fatal(err)
+	}
+	want := "## Title: Moonlight\n## Interface: 11507, 110200, 50500\nboot/boot.lua\n"
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("SetRetail() =\n%s\nwant\n%s", got, want)
+	}
+
+	if err := Parse([]byte("boot/boot.lua\n")).SetRetail(110200); err == nil {
+		t.Error("SetRetail() without ## Interface: succeeded, want an error")
+	}
+}
@@ -0,0 +1,122 @@
+package toc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LoadedFile is a file the client loads for a TOC.
+type LoadedFile struct {
+	// Path is the repo relative, forward slash path of the file.
+	Path string
+	// Entry is the TOC entry that causes the file to load, which is Path
+	// itself for files listed directly.
+	Entry string
+	// Via is the XML file that loads Path, or empty for listed files.
+	Via string
+}
+
+// LoadedFiles returns every file the client loads for this TOC, in load
+// order: each entry, and for XML entries, every file pulled in through
+// <Script file="..."/> and <Include file="..."/>, recursively. Paths are
+// repo relative with forward slashes. Files that do not exist are still
+// returned so that callers can report them.
+func (f *File) LoadedFiles(root string) ([]string, error) {
+	loads, err := f.Loads(root)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, len(loads))
+	for i, l := range loads {
+		files[i] = l.Path
+	}
+	return files, nil
+}
+
+// Loads is like LoadedFiles, but also records why each file is loaded.
+func (f *File) Loads(root string) ([]LoadedFile, error) {
+	var loads []LoadedFile
+	seen := make(map[string]bool)
+
+	var visit func(rel, entry, via string) error
+	visit = func(rel, entry, via string) error {
+		if seen[rel] {
+			return nil
+		}
+		seen[rel] = true
+		loads = append(loads, LoadedFile{Path: rel, Entry: entry, Via: via})
+		if !strings.EqualFold(path.Ext(rel), ".xml") {
+			return nil
+		}
+		refs, err := References(root, rel)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		for _, ref := range refs {
+			if err := visit(ref, entry, rel); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, entry := range f.Entries() {
+		if err := visit(entry, entry, ""); err != nil {
+			return nil, err
+		}
+	}
+	return loads, nil
+}
+
+// References returns the repo relative paths of the files that the XML file
+// at the repo relative path rel loads directly, through <Script file="..."/>
+// and <Include file="..."/>.
+func References(root, rel string) ([]string, error) {
+	refs, err := xmlReferences(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	for i, ref := range refs {
+		refs[i] = path.Join(path.Dir(rel), NormalizePath(ref))
+	}
+	return refs, nil
+}
+
+// xmlReferences returns the file attribute of every Script and Include
+// element in the XML file at path.
+func xmlReferences(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var refs []string
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok || (se.Name.Local != "Script" && se.Name.Local != "Include") {
+			continue
+		}
+		for _, attr := range se.Attr {
+			if attr.Name.Local == "file" && attr.Value != "" {
+				refs = append(refs, attr.Value)
+			}
+		}
+	}
+}
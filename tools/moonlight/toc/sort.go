@@ -0,0 +1,151 @@
+package toc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Sort returns the entries of the TOC in an order where every file scope
+// dependency loads before the file that uses it. The Head and Tail entries
+// stay pinned, and the remaining entries keep their current relative order
+// wherever the dependencies allow it.
+func (g *Graph) Sort(f *File) ([]string, error) {
+	entries := f.Entries()
+	for i, path := range entries {
+		if slices.Contains(entries[:i], path) {
+			return nil, fmt.Errorf("%s is listed more than once, run toc check", path)
+		}
+	}
+	var head, middle, tail []string
+	for _, path := range Head {
+		if slices.Contains(entries, path) {
+			head = append(head, path)
+		}
+	}
+	for _, path := range entries {
+		if !isPinned(path) {
+			middle = append(middle, path)
+		}
+	}
+	for _, path := range Tail {
+		if slices.Contains(entries, path) {
+			tail = append(tail, path)
+		}
+	}
+
+	// edges maps each entry to the entries that must load before it, and
+	// the dependency that requires it.
+	entryOf := g.entryOf()
+	edges := make(map[string]map[string]Dependency)
+	for _, d := range g.Dependencies {
+		from, to := entryOf[d.Provider], entryOf[d.Path]
+		if from == to {
+			continue
+		}
+		switch {
+		case slices.Contains(head, from) && (!slices.Contains(head, to) || slices.Index(head, from) < slices.Index(head, to)):
+			// Pinned head entries always load first.
+			continue
+		case slices.Contains(head, to) || slices.Contains(tail, from):
+			return nil, fmt.Errorf("%s, but %s is pinned to load after %s", d, d.Provider, to)
+		case slices.Contains(tail, to):
+			continue
+		}
+		if edges[to] == nil {
+			edges[to] = make(map[string]Dependency)
+		}
+		if _, ok := edges[to][from]; !ok {
+			edges[to][from] = d
+		}
+	}
+
+	// Repeatedly take the earliest entry whose dependencies are all placed.
+	placed := make(map[string]bool)
+	sorted := slices.Clone(head)
+	remaining := slices.Clone(middle)
+	for len(remaining) > 0 {
+		next := -1
+		for i, path := range remaining {
+			ready := true
+			for dep := range edges[path] {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			return nil, cycleError(remaining, edges)
+		}
+		placed[remaining[next]] = true
+		sorted = append(sorted, remaining[next])
+		remaining = slices.Delete(remaining, next, next+1)
+	}
+	return append(sorted, tail...), nil
+}
+
+// cycleError describes one dependency cycle among the remaining entries,
+// every one of which waits on another remaining entry.
+func cycleError(remaining []string, edges map[string]map[string]Dependency) error {
+	var path []string
+	seen := make(map[string]int)
+	current := remaining[0]
+	for {
+		if i, ok := seen[current]; ok {
+			path = path[i:]
+			break
+		}
+		seen[current] = len(path)
+		path = append(path, current)
+		var deps []string
+		for dep := range edges[current] {
+			if slices.Contains(remaining, dep) {
+				deps = append(deps, dep)
+			}
+		}
+		slices.Sort(deps)
+		current = deps[0]
+	}
+
+	var b strings.Builder
+	b.WriteString("dependency cycle between toc entries:")
+	for i, entry := range path {
+		dep := path[(i+1)%len(path)]
+		fmt.Fprintf(&b, "\n  %s needs %s: %s", entry, dep, edges[entry][dep])
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// Reorder rearranges the entries of the TOC into order, which must hold the
+// same entries. Comments, directives and blank lines keep their place, and
+// each entry line slot is filled with the next entry from order.
+func (f *File) Reorder(order []string) error {
+	entries := f.Entries()
+	if len(order) != len(entries) {
+		return fmt.Errorf("reorder needs %d entries, got %d", len(entries), len(order))
+	}
+	for _, path := range order {
+		if !slices.Contains(entries, path) {
+			return fmt.Errorf("%s is not listed in the toc", path)
+		}
+	}
+	lines := make(map[string]Line, len(entries))
+	for _, line := range f.Lines {
+		if line.Kind == Entry {
+			lines[line.Path] = line
+		}
+	}
+	next := 0
+	for i, line := range f.Lines {
+		if line.Kind == Entry {
+			f.Lines[i] = lines[order[next]]
+			next++
+		}
+	}
+	return nil
+}
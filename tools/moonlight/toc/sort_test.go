@@ -0,0 +1,130 @@
+package toc
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	files := map[string]string{
+		"boot/boot.lua": `---@return bag
+function Moonlight:GetBag()
+  return self.classes.bag
+end
+
+---@return item
+function Moonlight:GetItem()
+  return self.classes.item
+end
+`,
+		"pool/pool.lua":       "",
+		"constants/const.lua": "",
+		"boot/init.lua":       "",
+		"bag/bag.lua":         `local bag = moonlight:NewClass("bag")`,
+		"item/item.lua": `local bag = moonlight:GetBag()
+local item = moonlight:NewClass("item")
+`,
+		"frames/frames.xml": `<Ui><Script file="frame.lua"/></Ui>`,
+		"frames/frame.lua":  "local item = moonlight:GetItem()\n",
+		"other/other.lua":   "",
+	}
+	tests := []struct {
+		name string
+		// extra is written on top of files.
+		extra map[string]string
+		toc   string
+		// want is the sorted TOC, or the start of the error.
+		want    string
+		wantErr string
+	}{
+		{
+			name: "dependencies first",
+			toc: `## Interface: 110200
+boot/boot.lua
+pool/pool.lua
+constants/const.lua
+# frames
+frames/frames.xml
+item/item.lua
+
+bag/bag.lua
+other/other.lua
+boot/init.lua
+`,
+			want: `## Interface: 110200
+boot/boot.lua
+pool/pool.lua
+constants/const.lua
+# frames
+bag/bag.lua
+item/item.lua
+
+frames/frames.xml
+other/other.lua
+boot/init.lua
+`,
+		},
+		{
+			name: "sorted",
+			toc:  "boot/boot.lua\nbag/bag.lua\nitem/item.lua\nother/other.lua\n",
+			want: "boot/boot.lua\nbag/bag.lua\nitem/item.lua\nother/other.lua\n",
+		},
+		{
+			name: "pinned entries",
+			toc:  "other/other.lua\nboot/init.lua\nconstants/const.lua\nbag/bag.lua\nboot/boot.lua\n",
+			want: "boot/boot.lua\nconstants/const.lua\nother/other.lua\nbag/bag.lua\nboot/init.lua\n",
+		},
+		{
+			name:  "pinned tail uses a module",
+			extra: map[string]string{"boot/init.lua": "moonlight:GetBag()\n"},
+			toc:   "boot/boot.lua\nboot/init.lua\nbag/bag.lua\n",
+			want:  "boot/boot.lua\nbag/bag.lua\nboot/init.lua\n",
+		},
+		{
+			name:    "pinned head uses a module",
+			extra:   map[string]string{"constants/const.lua": "moonlight:GetBag()\n"},
+			toc:     "boot/boot.lua\nconstants/const.lua\nbag/bag.lua\n",
+			wantErr: "constants/const.lua:1 calls moonlight:GetBag() at file scope, but bag/bag.lua is pinned to load after constants/const.lua",
+		},
+		{
+			name:    "cycle",
+			extra:   map[string]string{"bag/bag.lua": "local bag = moonlight:NewClass(\"bag\")\nlocal item = moonlight:GetItem()\n"},
+			toc:     "boot/boot.lua\nother/other.lua\nitem/item.lua\nbag/bag.lua\n",
+			wantErr: "dependency cycle between toc entries:\n  item/item.lua needs bag/bag.lua: item/item.lua:1 calls moonlight:GetBag() at file scope\n  bag/bag.lua needs item/item.lua: bag/bag.lua:2 calls moonlight:GetItem() at file scope",
+		},
+		{
+			name:    "duplicate entry",
+			toc:     "boot/boot.lua\nbag/bag.lua\nbag/bag.lua\n",
+			wantErr: "bag/bag.lua is listed more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := maps.Clone(files)
+			maps.Copy(repo, tt.extra)
+			root := writeRepo(t, repo)
+			f := Parse([]byte(tt.toc))
+			g, err := LoadGraph(root, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, err := g.Sort(f)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("Sort() = %v, want an error starting with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Reorder(order); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(f.Bytes()); got != tt.want {
+				t.Errorf("sorted toc =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package toc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Head lists the entries that must load first, in this order.
+var Head = []string{"boot/boot.lua", "pool/pool.lua", "constants/const.lua"}
+
+// Tail lists the entries that must load last, in this order.
+var Tail = []string{"boot/init.lua"}
+
+// LineKind describes what a single line of a TOC file holds.
+type LineKind int
+
+const (
+	// Blank is an empty or whitespace only line.
+	Blank LineKind = iota
+	// Directive is a "## Key: Value" metadata line.
+	Directive
+	// Comment is a "#" comment line that is not a directive.
+	Comment
+	// Entry is a file path that the client loads.
+	Entry
+)
+
+// Line is a single line of a TOC file.
+type Line struct {
+	Kind LineKind
+	Text string
+	// Path is the normalized, forward slash file path for Entry lines.
+	Path string
+}
+
+// File is a parsed TOC file. Lines are kept verbatim so that writing
+// the file back out only changes what was explicitly edited.
+type File struct {
+	Lines []Line
+	// trailingNewline records if the source ended with a newline.
+	trailingNewline bool
+}
+
+// Parse parses the contents of a TOC file.
+func Parse(content []byte) *File {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	f := &File{trailingNewline: strings.HasSuffix(text, "\n")}
+	text = strings.TrimSuffix(text, "\n")
+	for _, raw := range strings.Split(text, "\n") {
+		f.Lines = append(f.Lines, parseLine(raw))
+	}
+	return f
+}
+
+// Load reads and parses the TOC file at path.
+func Load(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read toc file: %w", err)
+	}
+	return Parse(content), nil
+}
+
+func parseLine(raw string) Line {
+	trimmed := strings.TrimSpace(raw)
+	switch {
+	case trimmed == "":
+		return Line{Kind: Blank, Text: raw}
+	case strings.HasPrefix(trimmed, "##"):
+		return Line{Kind: Directive, Text: raw}
+	case strings.HasPrefix(trimmed, "#"):
+		return Line{Kind: Comment, Text: raw}
+	default:
+		return Line{Kind: Entry, Text: raw, Path: NormalizePath(trimmed)}
+	}
+}
+
+// NormalizePath converts a TOC or repo relative path to the forward slash
+// form used for comparisons.
+func NormalizePath(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	path = strings.TrimPrefix(path, "//")
+	return strings.TrimPrefix(path, "./")
+}
+
+// Entries returns the paths of all file entries, in load order.
+func (f *File) Entries() []string {
+	var entries []string
+	for _, line := range f.Lines {
+		if line.Kind == Entry {
+			entries = append(entries, line.Path)
+		}
+	}
+	return entries
+}
+
+// IndexOf returns the line index of the entry for path, or -1 if the
+// path is not listed.
+func (f *File) IndexOf(path string) int {
+	path = NormalizePath(path)
+	for i, line := range f.Lines {
+		if line.Kind == Entry && line.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove removes every entry for path and reports if anything was removed.
+func (f *File) Remove(path string) bool {
+	path = NormalizePath(path)
+	removed := false
+	lines := f.Lines[:0]
+	for _, line := range f.Lines {
+		if line.Kind == Entry && line.Path == path {
+			removed = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+	f.Lines = lines
+	return removed
+}
+
+// Rename replaces the entry for from with to, keeping its position, and
+// reports if the entry was found.
+func (f *File) Rename(from, to string) bool {
+	i := f.IndexOf(from)
+	if i == -1 {
+		return false
+	}
+	to = NormalizePath(to)
+	f.Lines[i] = Line{Kind: Entry, Text: to, Path: to}
+	return true
+}
+
+// Position controls where Insert places a new entry. At most one of After
+// and Before may be set; when neither is set the entry is placed next to
+// the other entries from the same directory.
+type Position struct {
+	After  string
+	Before string
+}
+
+// Insert adds path as a new entry, respecting the pinned Head and Tail entries.
+func (f *File) Insert(path string, pos Position) error {
+	path = NormalizePath(path)
+	if f.IndexOf(path) != -1 {
+		return fmt.Errorf("%s is already listed in the toc", path)
+	}
+	if isPinned(path) {
+		return fmt.Errorf("%s is a pinned toc entry and can not be inserted", path)
+	}
+
+	var at int
+	switch {
+	case pos.After != "" && pos.Before != "":
+		return fmt.Errorf("only one of after and before may be set")
+	case pos.After != "":
+		i := f.IndexOf(pos.After)
+		if i == -1 {
+			return fmt.Errorf("%s is not listed in the toc", NormalizePath(pos.After))
+		}
+		at = i + 1
+	case pos.Before != "":
+		i := f.IndexOf(pos.Before)
+		if i == -1 {
+			return fmt.Errorf("%s is not listed in the toc", NormalizePath(pos.Before))
+		}
+		at = f.leadingComments(i)
+	default:
+		at = f.defaultIndex(path)
+	}
+
+	if at <= f.headEnd() {
+		return fmt.Errorf("%s can not be placed before the pinned entries %s", path, strings.Join(Head, ", "))
+	}
+	if tail := f.tailStart(); at > tail {
+		return fmt.Errorf("%s can not be placed after the pinned entries %s", path, strings.Join(Tail, ", "))
+	}
+
+	line := Line{Kind: Entry, Text: path, Path: path}
+	f.Lines = slices.Insert(f.Lines, at, line)
+	return nil
+}
+
+// defaultIndex picks the line index for a new entry: after the last entry
+// from the same directory, then after the last unpinned entry of the same
+// type, and finally right before the tail entries.
+func (f *File) defaultIndex(path string) int {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	sameDir, sameExt := -1, -1
+	tail := f.tailStart()
+	for i, line := range f.Lines[:tail] {
+		if line.Kind != Entry || isPinned(line.Path) {
+			continue
+		}
+		if filepath.Dir(line.Path) == dir {
+			sameDir = i
+		}
+		if filepath.Ext(line.Path) == ext {
+			sameExt = i
+		}
+	}
+	switch {
+	case sameDir != -1:
+		return sameDir + 1
+	case sameExt != -1:
+		return sameExt + 1
+	default:
+		return max(tail, f.headEnd()+1)
+	}
+}
+
+// headEnd returns the line index of the last pinned head entry, or -1.
+func (f *File) headEnd() int {
+	end := -1
+	for _, path := range Head {
+		end = max(end, f.IndexOf(path))
+	}
+	return end
+}
+
+// tailStart returns the line index where the pinned tail entries, including
+// the comments directly above them, begin. Without tail entries this is
+// the end of the file.
+func (f *File) tailStart() int {
+	start := len(f.Lines)
+	for _, path := range Tail {
+		if i := f.IndexOf(path); i != -1 {
+			start = min(start, f.leadingComments(i))
+		}
+	}
+	return start
+}
+
+// leadingComments returns the index of the first comment line in the block
+// of comments directly above line i, or i if there are none.
+func (f *File) leadingComments(i int) int {
+	for i > 0 && f.Lines[i-1].Kind == Comment {
+		i--
+	}
+	return i
+}
+
+func isPinned(path string) bool {
+	return slices.Contains(Head, path) || slices.Contains(Tail, path)
+}
+
+// Bytes renders the TOC file back into its on disk form.
+func (f *File) Bytes() []byte {
+	var b strings.Builder
+	for i, line := range f.Lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(line.Text)
+	}
+	if f.trailingNewline {
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// UserConfig holds the per user settings of the tool, such as where the
+// game is installed. It is stored as JSON in the user config directory, so
+// it is shared by every checkout.
+type UserConfig struct {
+	// AddonsDir is the Interface/AddOns directory of the game.
+	AddonsDir string `json:"addonsDir,omitempty"`
+	// PublishEndpoint is the URL that release publish uploads to.
+	PublishEndpoint string `json:"publishEndpoint,omitempty"`
+	// PublishToken is the API token that release publish authenticates
+	// with.
+	PublishToken string `json:"publishToken,omitempty"`
+}
+
+// UserConfigPath returns the path of the user config file.
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "moonlight", "config.json"), nil
+}
+
+// LoadUserConfig reads the user config, or returns an empty config if the
+// file does not exist yet.
+func LoadUserConfig() (*UserConfig, error) {
+	path, err := UserConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &UserConfig{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse user config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the user config. It holds the publish token, so only the
+// user can read the file and its directory.
+func (c *UserConfig) Save() error {
+	path, err := UserConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode user config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create user config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write user config: %w", err)
+	}
+	// WriteFile keeps the mode of a file that already exists, such as one
+	// written by an older version.
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict user config permissions: %w", err)
+	}
+	return nil
+}
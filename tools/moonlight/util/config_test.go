@@ -0,0 +1,59 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUserConfigSave(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	path, err := UserConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A config written by an older version is readable by everyone.
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &UserConfig{PublishToken: "secret"}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("config mode = %v, want 0600", mode)
+	}
+	loaded, err := LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.PublishToken != "secret" {
+		t.Errorf("loaded token %q, want secret", loaded.PublishToken)
+	}
+
+	// A new config directory is only accessible by the user.
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "new"))
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if path, err = UserConfigPath(); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0700 {
+		t.Errorf("config directory mode = %v, want 0700", mode)
+	}
+}
@@ -0,0 +1,175 @@
+package vcs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Commit is a single commit in a Range.
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+	Author  string
+	Time    time.Time
+	// Files holds the repo relative paths the commit changed.
+	Files []string
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (c Commit) ShortHash() string {
+	return c.Hash[:min(7, len(c.Hash))]
+}
+
+// Range is the list of commits made after a tag, up to and including HEAD.
+type Range struct {
+	// Since is the tag the range starts after, or empty when the range
+	// holds the whole history.
+	Since string
+	// Commits are ordered newest first. Merge commits are left out.
+	Commits []Commit
+}
+
+// Log returns the commits made after since, up to HEAD of the repo at root.
+// When since is empty, the range starts at the latest tag that is reachable
+// from HEAD and is not on HEAD itself, so that a tagged release lists the
+// changes since the release before it.
+func Log(root, since string) (Range, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return Range{}, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return Range{}, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	r := Range{Since: since}
+	var base *plumbing.Hash
+	if since != "" {
+		base, err = repo.ResolveRevision(plumbing.Revision(since))
+		if err != nil {
+			return Range{}, fmt.Errorf("failed to resolve %s: %w", since, err)
+		}
+	} else {
+		r.Since, base, err = previousTag(repo, head.Hash())
+		if err != nil {
+			return Range{}, err
+		}
+	}
+
+	exclude := make(map[plumbing.Hash]bool)
+	if base != nil {
+		iter, err := repo.Log(&git.LogOptions{From: *base})
+		if err != nil {
+			return Range{}, fmt.Errorf("failed to read history: %w", err)
+		}
+		err = iter.ForEach(func(c *object.Commit) error {
+			exclude[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return Range{}, fmt.Errorf("failed to read history: %w", err)
+		}
+	}
+
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return Range{}, fmt.Errorf("failed to read history: %w", err)
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if exclude[c.Hash] || c.NumParents() > 1 {
+			return nil
+		}
+		files, err := changedFiles(c)
+		if err != nil {
+			return err
+		}
+		subject, body, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+		r.Commits = append(r.Commits, Commit{
+			Hash:    c.Hash.String(),
+			Subject: strings.TrimSpace(subject),
+			Body:    strings.TrimSpace(body),
+			Author:  c.Author.Name,
+			Time:    c.Committer.When,
+			Files:   files,
+		})
+		return nil
+	})
+	if err != nil {
+		return Range{}, fmt.Errorf("failed to read history: %w", err)
+	}
+	return r, nil
+}
+
+// previousTag finds the latest tag reachable from head, skipping tags on
+// head itself.
+func previousTag(repo *git.Repository, head plumbing.Hash) (string, *plumbing.Hash, error) {
+	tags, err := Tags(repo)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(tags) == 0 {
+		return "", nil, nil
+	}
+	iter, err := repo.Log(&git.LogOptions{From: head, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var (
+		name string
+		hash *plumbing.Hash
+	)
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Hash == head {
+			return nil
+		}
+		if names, ok := tags[c.Hash]; ok {
+			name, hash = names[len(names)-1], &c.Hash
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return name, hash, nil
+}
+
+// changedFiles returns the paths changed by a commit, compared to its first
+// parent.
+func changedFiles(c *object.Commit) ([]string, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", c.Hash, err)
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parent of %s: %w", c.Hash, err)
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to read tree of %s: %w", parent.Hash, err)
+		}
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %w", c.Hash, err)
+	}
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
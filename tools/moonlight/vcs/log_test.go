@@ -0,0 +1,156 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a git repo in a temp dir whose commits are a minute apart.
+type testRepo struct {
+	t    *testing.T
+	root string
+	repo *git.Repository
+	when time.Time
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRepo{t: t, root: root, repo: repo, when: time.Unix(1700000000, 0)}
+}
+
+// commit writes files, by slash separated relative path, and commits them.
+func (r *testRepo) commit(subject string, files ...string) plumbing.Hash {
+	r.t.Helper()
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	for _, rel := range files {
+		path := filepath.Join(r.root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(subject), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+		if _, err := wt.Add(rel); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.when = r.when.Add(time.Minute)
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: r.when}
+	hash, err := wt.Commit(subject, &git.CommitOptions{Author: sig, Committer: sig, AllowEmptyCommits: true})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return hash
+}
+
+// tag tags hash, with an annotated tag when message is set.
+func (r *testRepo) tag(name string, hash plumbing.Hash, message string) {
+	r.t.Helper()
+	var opts *git.CreateTagOptions
+	if message != "" {
+		opts = &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: r.when},
+			Message: message,
+		}
+	}
+	if _, err := r.repo.CreateTag(name, hash, opts); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func subjects(r Range) []string {
+	var s []string
+	for _, c := range r.Commits {
+		s = append(s, c.Subject)
+	}
+	return s
+}
+
+func TestLog(t *testing.T) {
+	r := newTestRepo(t)
+	first := r.commit("first", "Moonlight.toc")
+	r.tag("v1.0.0", first, "release 1.0.0")
+	r.commit("second", "bag/bag.lua")
+	third := r.commit("third", "sonata/engine.lua", "Moonlight.toc")
+	r.tag("v1.1.0-rc.1", third, "")
+	r.tag("v1.1.0", third, "release 1.1.0")
+	r.tag("nightly", third, "")
+	r.commit("fourth", "bag/bag.lua")
+	fifth := r.commit("fifth")
+
+	tests := []struct {
+		name      string
+		since     string
+		wantSince string
+		want      []string
+	}{
+		{"after the latest tag", "", "v1.1.0", []string{"fifth", "fourth"}},
+		{"after an older tag", "v1.0.0", "v1.0.0", []string{"fifth", "fourth", "third", "second"}},
+		{"after a pre-release", "v1.1.0-rc.1", "v1.1.0-rc.1", []string{"fifth", "fourth"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Log(r.root, tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Since != tt.wantSince || !slices.Equal(subjects(got), tt.want) {
+				t.Errorf("Log(%q) = %s %q, want %s %q", tt.since, got.Since, subjects(got), tt.wantSince, tt.want)
+			}
+		})
+	}
+
+	got, err := Log(r.root, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := [][]string{nil, {"bag/bag.lua"}, {"Moonlight.toc", "sonata/engine.lua"}, {"bag/bag.lua"}}
+	for i, c := range got.Commits {
+		if !slices.Equal(c.Files, wantFiles[i]) {
+			t.Errorf("files of %s = %q, want %q", c.Subject, c.Files, wantFiles[i])
+		}
+	}
+
+	// A tag on HEAD is the release being made, so its changes start at the
+	// tag before it.
+	r.tag("v1.2.0", fifth, "release 1.2.0")
+	got, err = Log(r.root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Since != "v1.1.0" || !slices.Equal(subjects(got), []string{"fifth", "fourth"}) {
+		t.Errorf("Log() on a tagged HEAD = %s %q, want v1.1.0 [fifth fourth]", got.Since, subjects(got))
+	}
+
+	if _, err := Log(r.root, "v9.9.9"); err == nil {
+		t.Error("Log() after an unknown tag succeeded, want an error")
+	}
+}
+
+func TestLogWithoutTags(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("first", "Moonlight.toc")
+	r.commit("second", "bag/bag.lua")
+	got, err := Log(r.root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Since != "" || !slices.Equal(subjects(got), []string{"second", "first"}) {
+		t.Errorf("Log() = %s %q, want the whole history", got.Since, subjects(got))
+	}
+}
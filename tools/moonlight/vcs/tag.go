@@ -0,0 +1,66 @@
+package vcs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TagNames returns the name of every tag in the repo at root, and the names
+// of the tags on HEAD.
+func TagNames(root string) (all, head []string, err error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	tags, err := Tags(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	for hash, names := range tags {
+		all = append(all, names...)
+		if hash == ref.Hash() {
+			head = append(head, names...)
+		}
+	}
+	return all, head, nil
+}
+
+// CreateTag creates an annotated tag on HEAD of the repo at root. The
+// tagger is the git user.name and user.email.
+func CreateTag(root, name, message string) error {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return fmt.Errorf("failed to open git repository: %w", err)
+	}
+	cfg, err := repo.ConfigScoped(config.GlobalScope)
+	if err != nil {
+		return fmt.Errorf("failed to read git config: %w", err)
+	}
+	if cfg.User.Name == "" || cfg.User.Email == "" {
+		return fmt.Errorf("git user.name and user.email must be set to create a tag")
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	_, err = repo.CreateTag(name, head.Hash(), &git.CreateTagOptions{
+		Tagger: &object.Signature{
+			Name:  cfg.User.Name,
+			Email: cfg.User.Email,
+			When:  time.Now(),
+		},
+		Message: message,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package vcs reads version information from the git repository that
+// holds the addon.
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cidan/Moonlight/tools/moonlight/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Revision describes the commit a build is made from.
+type Revision struct {
+	// Hash is the full hash of the HEAD commit.
+	Hash string
+	// Time is the committer time of the HEAD commit.
+	Time time.Time
+	// Tag is the most recent tag reachable from HEAD, or empty.
+	Tag string
+	// Distance is the number of commits between Tag and HEAD.
+	Distance int
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (r Revision) ShortHash() string {
+	if len(r.Hash) < 7 {
+		return r.Hash
+	}
+	return r.Hash[:7]
+}
+
+// Version returns the version string for the revision, following git
+// describe: the tag when HEAD is tagged, "<tag>-<distance>-g<hash>" when
+// commits were made since the tag, or the short hash without any tag.
+func (r Revision) Version() string {
+	switch {
+	case r.Tag == "":
+		return r.ShortHash()
+	case r.Distance == 0:
+		return r.Tag
+	default:
+		return fmt.Sprintf("%s-%d-g%s", r.Tag, r.Distance, r.ShortHash())
+	}
+}
+
+// Head returns the revision of the HEAD commit of the repo at root.
+func Head(root string) (Revision, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+	rev := Revision{Hash: commit.Hash.String(), Time: commit.Committer.When}
+
+	tags, err := Tags(repo)
+	if err != nil {
+		return Revision{}, err
+	}
+	if len(tags) == 0 {
+		return rev, nil
+	}
+
+	log, err := repo.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to read history: %w", err)
+	}
+	distance := 0
+	err = log.ForEach(func(c *object.Commit) error {
+		if names, ok := tags[c.Hash]; ok {
+			rev.Tag = names[len(names)-1]
+			rev.Distance = distance
+			return storer.ErrStop
+		}
+		distance++
+		return nil
+	})
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to read history: %w", err)
+	}
+	return rev, nil
+}
+
+// Tags maps every tagged commit in the repo to its tag names, sorted by
+// version with the highest last.
+// Annotated tags are resolved to the commit they point at.
+func Tags(repo *git.Repository) (map[plumbing.Hash][]string, error) {
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	tags := make(map[plumbing.Hash][]string)
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		tag, err := repo.TagObject(hash)
+		switch {
+		case err == nil:
+			commit, err := tag.Commit()
+			if err != nil {
+				// Tags of trees or blobs do not version anything.
+				return nil
+			}
+			hash = commit.Hash
+		case !errors.Is(err, plumbing.ErrObjectNotFound):
+			return err
+		}
+		tags[hash] = append(tags[hash], ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+	for _, names := range tags {
+		slices.SortFunc(names, compareTags)
+	}
+	return tags, nil
+}
+
+// compareTags orders tag names by version, so that v1.10.0 sorts after
+// v1.9.0. Tags that are not semantic versions sort before the versions, by
+// name.
+func compareTags(a, b string) int {
+	va, okA := semver.Parse(a)
+	vb, okB := semver.Parse(b)
+	switch {
+	case okA && okB:
+		if c := va.Compare(vb); c != 0 {
+			return c
+		}
+	case okA:
+		return 1
+	case okB:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// Dirty reports if the worktree of the repo at root has uncommitted
+// changes, including untracked files that are not ignored.
+func Dirty(root string) (bool, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return false, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	return !status.IsClean(), nil
+}